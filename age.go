@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/olekukonko/tablewriter"
+)
+
+// ageBucket is a range of code ages used by the age distribution
+type ageBucket struct {
+	Label   string
+	MaxDays int // Upper bound (exclusive) of the bucket in days, 0 for no bound
+}
+
+// ageBuckets are the age ranges surviving lines are grouped into
+var ageBuckets = []ageBucket{
+	{"< 1 month", 30},
+	{"1-3 months", 91},
+	{"3-6 months", 182},
+	{"6-12 months", 365},
+	{"1-2 years", 730},
+	{"> 2 years", 0},
+}
+
+// AgeDistribution counts surviving lines per age bucket
+type AgeDistribution struct {
+	Buckets    []int // Lines per entry in ageBuckets
+	TotalLines int
+}
+
+// SurvivalPoint holds how many of the lines added in a month still exist at HEAD
+type SurvivalPoint struct {
+	Month     string // Month key, "YYYY-MM"
+	Start     time.Time
+	Added     int
+	Surviving int
+}
+
+// CodeAgeStats holds the age of the code at HEAD, based on blame
+type CodeAgeStats struct {
+	Reference   time.Time // Ages are measured relative to the HEAD commit date
+	Repository  *AgeDistribution
+	Directories map[string]*AgeDistribution
+	Files       map[string]*AgeDistribution
+	Survival    []*SurvivalPoint // Oldest month first
+}
+
+// newAgeDistribution creates an empty age distribution
+func newAgeDistribution() *AgeDistribution {
+	return &AgeDistribution{Buckets: make([]int, len(ageBuckets))}
+}
+
+// add counts a line of the given age
+func (d *AgeDistribution) add(age time.Duration) {
+	days := int(age.Hours() / 24)
+	for i, bucket := range ageBuckets {
+		if bucket.MaxDays == 0 || days < bucket.MaxDays {
+			d.Buckets[i]++
+			break
+		}
+	}
+	d.TotalLines++
+}
+
+// SurvivalCollector counts the lines added per month from a stream of commit records
+type SurvivalCollector struct {
+	stats   *RepositoryStats
+	points  map[string]*SurvivalPoint
+	commits map[plumbing.Hash]bool // Commits whose lines count as surviving
+}
+
+// NewSurvivalCollector creates a collector bucketing months like stats does
+func NewSurvivalCollector(stats *RepositoryStats) *SurvivalCollector {
+	return &SurvivalCollector{
+		stats:   stats,
+		points:  make(map[string]*SurvivalPoint),
+		commits: make(map[plumbing.Hash]bool),
+	}
+}
+
+// point returns the survival point for the month containing t
+func (c *SurvivalCollector) point(t time.Time) *SurvivalPoint {
+	key, start := periodBucket(localTime(c.stats, t), PeriodMonth, c.stats.WeekStart)
+	point, ok := c.points[key]
+	if !ok {
+		point = &SurvivalPoint{Month: key, Start: start}
+		c.points[key] = point
+	}
+	return point
+}
+
+// Add counts the lines added by a commit. It can be passed directly to WalkCommits.
+func (c *SurvivalCollector) Add(record *CommitRecord) error {
+	c.commits[plumbing.NewHash(record.Hash)] = true
+	point := c.point(record.When)
+	for _, file := range record.Files {
+		point.Added += file.Additions
+	}
+	return nil
+}
+
+// AnalyzeCodeAge blames every file at HEAD and buckets the surviving lines by
+// the age of the commit that introduced them. When survival is not nil, the
+// surviving lines are also matched against the lines added per month; only
+// lines from commits passed to the collector count as surviving.
+func AnalyzeCodeAge(repo *git.Repository, stats *RepositoryStats, survival *SurvivalCollector) (*CodeAgeStats, error) {
+	// Measure ages relative to the HEAD commit
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	age := &CodeAgeStats{
+		Reference:   head.Author.When,
+		Repository:  newAgeDistribution(),
+		Directories: make(map[string]*AgeDistribution),
+		Files:       make(map[string]*AgeDistribution),
+	}
+
+	err = blameFiles(repo, stats, func(filePath string, lines []*git.Line) error {
+		file := newAgeDistribution()
+		dir := path.Dir(filePath)
+		if age.Directories[dir] == nil {
+			age.Directories[dir] = newAgeDistribution()
+		}
+
+		for _, line := range lines {
+			lineAge := age.Reference.Sub(line.Date)
+			file.add(lineAge)
+			age.Directories[dir].add(lineAge)
+			age.Repository.add(lineAge)
+			if survival != nil && survival.commits[line.Hash] {
+				survival.point(line.Date).Surviving++
+			}
+		}
+
+		age.Files[filePath] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Order the survival curve by month
+	if survival != nil {
+		for _, point := range survival.points {
+			age.Survival = append(age.Survival, point)
+		}
+		sort.Slice(age.Survival, func(i, j int) bool {
+			return age.Survival[i].Start.Before(age.Survival[j].Start)
+		})
+	}
+
+	return age, nil
+}
+
+// survivalPercent returns the percentage of a month's added lines that survive
+func survivalPercent(point *SurvivalPoint) float64 {
+	if point.Added == 0 {
+		return 0
+	}
+	return float64(point.Surviving) / float64(point.Added) * 100
+}
+
+// sortedKeys returns the keys of an age distribution map in sorted order
+func sortedKeys(distributions map[string]*AgeDistribution) []string {
+	keys := make([]string, 0, len(distributions))
+	for key := range distributions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeCodeAge writes the code age report in the requested format
+func writeCodeAge(age *CodeAgeStats, format string) error {
+	switch format {
+	case FormatJSON:
+		type distributionRecord struct {
+			Scope      string         `json:"scope"`
+			Path       string         `json:"path,omitempty"`
+			Buckets    map[string]int `json:"buckets"`
+			TotalLines int            `json:"total_lines"`
+		}
+		type survivalRecord struct {
+			Month     string  `json:"month"`
+			Added     int     `json:"added"`
+			Surviving int     `json:"surviving"`
+			Percent   float64 `json:"surviving_percent"`
+		}
+		record := func(scope, path string, d *AgeDistribution) distributionRecord {
+			buckets := make(map[string]int, len(ageBuckets))
+			for i, bucket := range ageBuckets {
+				buckets[bucket.Label] = d.Buckets[i]
+			}
+			return distributionRecord{scope, path, buckets, d.TotalLines}
+		}
+
+		distributions := []distributionRecord{record("repository", "", age.Repository)}
+		for _, dir := range sortedKeys(age.Directories) {
+			distributions = append(distributions, record("directory", dir, age.Directories[dir]))
+		}
+		for _, file := range sortedKeys(age.Files) {
+			distributions = append(distributions, record("file", file, age.Files[file]))
+		}
+		survival := []survivalRecord{}
+		for _, point := range age.Survival {
+			survival = append(survival, survivalRecord{point.Month, point.Added, point.Surviving, survivalPercent(point)})
+		}
+		return writeJSON(struct {
+			Reference     string               `json:"reference"`
+			Distributions []distributionRecord `json:"distributions"`
+			Survival      []survivalRecord     `json:"survival"`
+		}{age.Reference.Format(time.RFC3339), distributions, survival})
+	case FormatCSV:
+		header := []string{"scope", "path"}
+		for _, bucket := range ageBuckets {
+			header = append(header, bucket.Label)
+		}
+		rows := [][]string{append(header, "total_lines")}
+		appendRow := func(scope, path string, d *AgeDistribution) {
+			row := []string{scope, path}
+			for _, count := range d.Buckets {
+				row = append(row, fmt.Sprintf("%d", count))
+			}
+			rows = append(rows, append(row, fmt.Sprintf("%d", d.TotalLines)))
+		}
+		appendRow("repository", "", age.Repository)
+		for _, dir := range sortedKeys(age.Directories) {
+			appendRow("directory", dir, age.Directories[dir])
+		}
+		for _, file := range sortedKeys(age.Files) {
+			appendRow("file", file, age.Files[file])
+		}
+		return writeCSV(rows)
+	default:
+		displayCodeAge(age)
+		return nil
+	}
+}
+
+// displayCodeAge displays the age distribution and survival curve in ASCII tables
+func displayCodeAge(age *CodeAgeStats) {
+	header := []string{"Path"}
+	for _, bucket := range ageBuckets {
+		header = append(header, bucket.Label)
+	}
+	header = append(header, "Lines")
+
+	// Age distribution per directory and per file
+	for _, distributions := range []map[string]*AgeDistribution{age.Directories, age.Files} {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(header)
+		table.SetBorder(true)
+		table.SetAutoFormatHeaders(false)
+		for _, key := range sortedKeys(distributions) {
+			table.Append(ageRow(key, distributions[key]))
+		}
+		table.Append(ageRow("TOTAL", age.Repository))
+		table.Render()
+	}
+
+	// Survival of the lines added each month
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Month", "Lines Added", "Surviving", "Surviving %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, point := range age.Survival {
+		table.Append([]string{
+			point.Month,
+			fmt.Sprintf("%d", point.Added),
+			fmt.Sprintf("%d", point.Surviving),
+			fmt.Sprintf("%.1f%%", survivalPercent(point)),
+		})
+	}
+	table.Render()
+}
+
+// ageRow formats an age distribution as a table row with percentages
+func ageRow(label string, d *AgeDistribution) []string {
+	row := []string{label}
+	for _, count := range d.Buckets {
+		percent := 0.0
+		if d.TotalLines > 0 {
+			percent = float64(count) / float64(d.TotalLines) * 100
+		}
+		row = append(row, fmt.Sprintf("%.1f%%", percent))
+	}
+	return append(row, fmt.Sprintf("%d", d.TotalLines))
+}
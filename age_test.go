@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgeDistributionBuckets(t *testing.T) {
+	d := newAgeDistribution()
+	day := 24 * time.Hour
+	for _, age := range []time.Duration{0, 10 * day, 45 * day, 200 * day, 400 * day, 1000 * day} {
+		d.add(age)
+	}
+
+	want := []int{2, 1, 0, 1, 1, 1}
+	for i, count := range want {
+		if d.Buckets[i] != count {
+			t.Errorf("Bucket %q: expected %d lines, got %d", ageBuckets[i].Label, count, d.Buckets[i])
+		}
+	}
+	if d.TotalLines != 6 {
+		t.Errorf("Expected 6 lines, got %d", d.TotalLines)
+	}
+}
+
+func TestAnalyzeCodeAge(t *testing.T) {
+	r := newTestRepo(t)
+	january := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", january, map[string]string{"src/a.go": "one\ntwo\nthree\nfour\n"})
+	// Two of January's lines are rewritten in June
+	r.commit("Bob", january.AddDate(0, 5, 0), map[string]string{"src/a.go": "one\ntwo\nTHREE\nFOUR\n"})
+
+	stats := newTestStats()
+	survival := NewSurvivalCollector(stats)
+	err := WalkCommits(r.repo, stats, func(record *CommitRecord) error {
+		aggregateCommit(stats, record)
+		return survival.Add(record)
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+
+	age, err := AnalyzeCodeAge(r.repo, stats, survival)
+	if err != nil {
+		t.Fatalf("Failed to analyze code age: %v", err)
+	}
+
+	// Relative to HEAD, June's lines are new and January's are 3-6 months old
+	if age.Repository.Buckets[0] != 2 || age.Repository.Buckets[2] != 2 {
+		t.Errorf("Unexpected repository distribution: %v", age.Repository.Buckets)
+	}
+	if age.Directories["src"] == nil || age.Files["src/a.go"].TotalLines != 4 {
+		t.Errorf("Expected per-directory and per-file distributions")
+	}
+
+	if len(age.Survival) != 2 {
+		t.Fatalf("Expected 2 survival points, got %d", len(age.Survival))
+	}
+	june := age.Survival[1]
+	if june.Month != "2025-06" || june.Added != 2 || june.Surviving != 2 {
+		t.Errorf("Unexpected June survival: %+v", june)
+	}
+	if jan := age.Survival[0]; jan.Month != "2025-01" || jan.Added != 4 || jan.Surviving != 2 {
+		t.Errorf("Unexpected January survival: %+v", jan)
+	}
+}
+
+func TestSurvivalOnlyCountsWalkedCommits(t *testing.T) {
+	r := newTestRepo(t)
+	january := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", january, map[string]string{"a.go": "one\ntwo\n"})
+	// Bob's lines survive but his commit is left out by -since
+	r.commit("Bob", january.AddDate(0, 0, 1), map[string]string{"b.go": "one\ntwo\nthree\n"})
+	r.commit("Carol", january.AddDate(0, 0, 2), map[string]string{"c.go": "one\n"})
+
+	stats := newTestStats()
+	stats.Since = january.AddDate(0, 0, 2)
+	survival := NewSurvivalCollector(stats)
+	if err := WalkCommits(r.repo, stats, survival.Add); err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+	age, err := AnalyzeCodeAge(r.repo, stats, survival)
+	if err != nil {
+		t.Fatalf("Failed to analyze code age: %v", err)
+	}
+
+	// Only Carol's line was both added and kept within the analyzed commits
+	if len(age.Survival) != 1 {
+		t.Fatalf("Expected 1 survival point, got %d", len(age.Survival))
+	}
+	if jan := age.Survival[0]; jan.Added != 1 || jan.Surviving != 1 || survivalPercent(jan) != 100 {
+		t.Errorf("Expected Carol's line alone to survive, got %+v", jan)
+	}
+	if age.Repository.TotalLines != 6 {
+		t.Errorf("Expected the age distribution to keep every line, got %d", age.Repository.TotalLines)
+	}
+}
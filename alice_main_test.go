@@ -112,7 +112,7 @@ func TestAliceMainFunctionality(t *testing.T) {
 	}
 
 	// Analyze the repository
-	err = AnalyzeRepository(repo, stats)
+	err = analyzeRepository(repo, stats)
 	if err != nil {
 		t.Fatalf("Failed to analyze repository: %v", err)
 	}
@@ -141,7 +141,7 @@ func TestAliceMainFunctionality(t *testing.T) {
 	os.Stdout = pipeWriter
 
 	// Display the stats
-	DisplayStats(stats)
+	displayStats(stats)
 
 	// Restore stdout
 	pipeWriter.Close()
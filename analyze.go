@@ -6,23 +6,52 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// analyzeRepository analyzes the Git repository and collects statistics
+// analyzeRepository analyzes the Git repository and collects statistics into
+// stats. Set stats.OnProgress to be notified after each processed commit.
 func analyzeRepository(repo *git.Repository, stats *RepositoryStats) error {
-	// Get the HEAD reference
-	ref, err := repo.Head()
+	return WalkCommits(repo, stats, func(record *CommitRecord) error {
+		aggregateCommit(stats, record)
+		return nil
+	})
+}
+
+// CommitFunc is called by WalkCommits for every commit that passed the filters
+type CommitFunc func(record *CommitRecord) error
+
+// WalkCommits walks the history reachable from HEAD, or from stats.Revision,
+// and calls fn with a CommitRecord for each commit touching files allowed by
+// the FileFilter and IgnoreFiles settings in stats. Returning an error from
+// fn stops the walk.
+func WalkCommits(repo *git.Repository, stats *RepositoryStats, fn CommitFunc) error {
+	// Resolve the commit the walk starts from
+	start, err := resolveRevision(repo, stats.Revision)
 	if err != nil {
 		return err
 	}
 
 	// Get the commit object
-	commit, err := repo.CommitObject(ref.Hash())
+	commit, err := repo.CommitObject(start)
 	if err != nil {
 		return err
 	}
 
+	// Find the commits the start of a range leaves out
+	var excluded map[plumbing.Hash]bool
+	if stats.BaseRevision != "" {
+		base, err := resolveRevision(repo, stats.BaseRevision)
+		if err != nil {
+			return err
+		}
+		if excluded, err = reachableCommits(repo, base); err != nil {
+			return err
+		}
+	}
+
 	// Create a commit iterator
 	commitIter, err := repo.Log(&git.LogOptions{From: commit.Hash})
 	if err != nil {
@@ -30,129 +59,291 @@ func analyzeRepository(repo *git.Repository, stats *RepositoryStats) error {
 	}
 	defer commitIter.Close()
 
+	started := time.Now()
+	processed := 0
+	options := diffOptionsFor(stats)
+
 	// Iterate through commits
-	err = commitIter.ForEach(func(c *object.Commit) error {
-		// Get author name
-		authorName := c.Author.Name
+	return commitIter.ForEach(func(c *object.Commit) error {
+		// Report progress once this commit has been processed
+		if stats.OnProgress != nil {
+			defer func() {
+				processed++
+				rate := 0.0
+				if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+					rate = float64(processed) / elapsed
+				}
+				stats.OnProgress(Progress{
+					Commits: processed,
+					Date:    c.Author.When,
+					Rate:    rate,
+				})
+			}()
+		}
+
+		// Skip commits outside the requested revision range
+		if excluded[c.Hash] {
+			return nil
+		}
 
-		// Variables to track if this commit should be counted
-		commitAffectsFilteredFiles := false
-		linesChanged := 0
+		record, err := commitRecord(repo, stats, c, options)
+		if err != nil || record == nil {
+			return err
+		}
+		return fn(record)
+	})
+}
+
+// commitRecord builds the CommitRecord of a single commit, or returns nil when
+// the settings in stats leave the commit out
+func commitRecord(repo *git.Repository, stats *RepositoryStats, c *object.Commit, options diffOptions) (*CommitRecord, error) {
+	// Skip commits outside the requested date range
+	if !inDateRange(stats, c.Author.When) {
+		return nil, nil
+	}
+
+	// Skip bulk mechanical commits listed in the ignore-revs file
+	if stats.IgnoreRevs[c.Hash.String()] {
+		stats.IgnoredCommits++
+		return nil, nil
+	}
 
-		// Get commit stats
-		if c.NumParents() > 0 {
-			// For non-initial commits, compare with parent
-			parent, err := c.Parent(0)
+	// Skip commits by bots when they are excluded
+	if stats.Bots == BotsExclude && isBot(stats, c.Author.Name, c.Author.Email) {
+		return nil, nil
+	}
+
+	// Skip merge commits when the merge policy excludes them
+	if stats.SkipMerges && c.NumParents() > 1 {
+		return nil, nil
+	}
+
+	record := &CommitRecord{
+		Hash:   c.Hash.String(),
+		Author: resolveAlias(stats.Aliases, c.Author.Name, c.Author.Email),
+		Email:  c.Author.Email,
+		When:   c.Author.When,
+		Files:  []FileChange{},
+	}
+
+	// Whether the commit touches files matching our filter, including
+	// files left out for only changing whitespace
+	touched := false
+
+	// Get commit stats
+	if c.NumParents() > 0 {
+		// For non-initial commits, compare with parent
+		parent, err := c.Parent(0)
+		if err == nil {
+			patch, err := parent.Patch(c)
 			if err == nil {
-				patch, err := parent.Patch(c)
-				if err == nil {
-					for _, fileStat := range patch.Stats() {
-						// Check if file should be included based on filter and ignore rules
-						if shouldIncludeFile(fileStat.Name, stats.FileFilter, stats.IgnoreFiles) {
-							commitAffectsFilteredFiles = true
-							linesChanged += fileStat.Addition + fileStat.Deletion
+				for _, filePatch := range patch.FilePatches() {
+					// Diff whitespace-normalized content instead of the raw patch
+					if stats.IgnoreWhitespace {
+						if filePatch, err = ignoreWhitespacePatch(repo, filePatch); err != nil {
+							return nil, err
 						}
 					}
+
+					change, ok := fileChangeFromPatch(filePatch, options)
+
+					// Check if file should be included based on filter and ignore rules
+					if !ok || !stats.IncludesFile(change.Name) {
+						continue
+					}
+					touched = true
+
+					// Leave out files with nothing but whitespace changes; the
+					// commit itself still counts
+					if stats.IgnoreWhitespace && change.Additions+change.Deletions == 0 {
+						continue
+					}
+					record.Files = append(record.Files, change)
 				}
 			}
-		} else {
-			// For initial commit, count all lines as additions
-			files, err := c.Files()
-			if err == nil {
-				err = files.ForEach(func(f *object.File) error {
-					if shouldIncludeFile(f.Name, stats.FileFilter, stats.IgnoreFiles) {
-						commitAffectsFilteredFiles = true
-						content, err := f.Contents()
-						if err == nil {
-							lineCount := len(strings.Split(content, "\n"))
-							linesChanged += lineCount
+		}
+	} else {
+		// For initial commit, count all lines as additions
+		files, err := c.Files()
+		if err == nil {
+			err = files.ForEach(func(f *object.File) error {
+				if stats.IncludesFile(f.Name) {
+					change := FileChange{Name: f.Name}
+					content, err := f.Contents()
+					if err == nil {
+						change.Additions = len(splitLines(content))
+						if options.ClassifyLines {
+							classifyContent(&change, content)
 						}
 					}
-					return nil
-				})
-			}
+					if options.KeepOps {
+						change.ops = []lineOp{{Type: fdiff.Add, Lines: change.Additions}}
+					}
+					record.Files = append(record.Files, change)
+					touched = true
+				}
+				return nil
+			})
 		}
+	}
 
-		// Only count this commit if it affects files matching our filter
-		if commitAffectsFilteredFiles {
-			// Get or create author stats
-			authorStats, ok := stats.Authors[authorName]
-			if !ok {
-				authorStats = &AuthorStats{
-					Name: authorName,
-				}
-				stats.Authors[authorName] = authorStats
-			}
+	// Only report this commit if it affects files matching our filter
+	if !touched {
+		return nil, nil
+	}
 
-			// Increment commit count
-			authorStats.CommitCount++
-			stats.TotalCommits++
-
-			// Add lines changed
-			authorStats.LinesChanged += linesChanged
-			stats.TotalLines += linesChanged
-
-			// Get the week start date (Sunday)
-			commitTime := c.Author.When
-			year, week := commitTime.ISOWeek()
-			weekStart := getWeekStart(year, week)
-			weekKey := fmt.Sprintf("%d-W%02d", year, week)
-
-			// Get or create weekly stats
-			weeklyStats, ok := stats.WeeklyStats[weekKey]
-			if !ok {
-				weeklyStats = &WeeklyStats{
-					Week:    weekStart,
-					Authors: make(map[string]*WeeklyAuthorStats),
-				}
-				stats.WeeklyStats[weekKey] = weeklyStats
-			}
+	// Skip commits that only touch comments and blank lines
+	if stats.SkipNonCode && record.CodeLinesChanged() == 0 {
+		return nil, nil
+	}
+	return record, nil
+}
 
-			// Get or create weekly author stats
-			weeklyAuthorStats, ok := weeklyStats.Authors[authorName]
-			if !ok {
-				weeklyAuthorStats = &WeeklyAuthorStats{
-					Name: authorName,
-					Week: weekStart,
-				}
-				weeklyStats.Authors[authorName] = weeklyAuthorStats
+// aggregateCommit adds a single commit record to the repository statistics
+func aggregateCommit(stats *RepositoryStats, record *CommitRecord) {
+	authorName := record.Author
+	linesChanged := record.LinesChanged()
+
+	// Get or create author stats
+	authorStats, ok := stats.Authors[authorName]
+	if !ok {
+		authorStats = &AuthorStats{
+			Name:   authorName,
+			Bot:    isBot(stats, authorName, record.Email),
+			Emails: make(map[string]int),
+		}
+		stats.Authors[authorName] = authorStats
+	}
+	authorStats.Emails[strings.ToLower(record.Email)]++
+
+	// Track the author's first and last activity
+	if authorStats.FirstCommit.IsZero() || record.When.Before(authorStats.FirstCommit) {
+		authorStats.FirstCommit = record.When
+	}
+	if record.When.After(authorStats.LastCommit) {
+		authorStats.LastCommit = record.When
+	}
+
+	// Increment commit count
+	authorStats.CommitCount++
+	stats.TotalCommits++
+
+	// Add lines changed
+	authorStats.LinesChanged += linesChanged
+	authorStats.LinesAdded += record.LinesAdded()
+	authorStats.CodeLinesChanged += record.CodeLinesChanged()
+	stats.TotalLines += linesChanged
+	stats.TotalCodeLines += record.CodeLinesChanged()
+
+	// Update the per-file churn
+	if stats.Files == nil {
+		stats.Files = make(map[string]*FileStats)
+	}
+	for _, change := range record.Files {
+		fileStats, ok := stats.Files[change.Name]
+		if !ok {
+			fileStats = &FileStats{
+				Name:    change.Name,
+				Authors: make(map[string]int),
 			}
+			stats.Files[change.Name] = fileStats
+		}
+		fileStats.CommitCount++
+		fileStats.LinesAdded += change.Additions
+		fileStats.LinesDeleted += change.Deletions
+		fileStats.Authors[authorName]++
+	}
 
-			// Update weekly stats
-			weeklyAuthorStats.CommitCount++
-			weeklyAuthorStats.LinesChanged += linesChanged
-			weeklyStats.TotalCommits++
-			weeklyStats.TotalLines += linesChanged
+	// Update the weekly and period time series
+	when := localTime(stats, record.When)
+	addToPeriod(stats.WeeklyStats, PeriodWeek, stats.WeekStart, when, record)
+	if stats.Periods == nil {
+		stats.Periods = make(map[string]*PeriodStats)
+	}
+	addToPeriod(stats.Periods, stats.Period, stats.WeekStart, when, record)
+}
+
+// resolveRevision resolves a branch, tag or commit hash to a commit hash,
+// or HEAD when revision is empty
+func resolveRevision(repo *git.Repository, revision string) (plumbing.Hash, error) {
+	if revision == "" {
+		ref, err := repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, err
 		}
+		return ref.Hash(), nil
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolving %s: %w", revision, err)
+	}
+	return *hash, nil
+}
+
+// reachableCommits returns the commits in the history of from, including from
+func reachableCommits(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	commitIter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+	defer commitIter.Close()
 
+	reachable := make(map[plumbing.Hash]bool)
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		reachable[c.Hash] = true
 		return nil
 	})
+	return reachable, err
+}
+
+// getWeekStart returns the start date (Monday) of the given ISO week
+func getWeekStart(year, week int, loc *time.Location) time.Time {
+	// ISO week 1 is the week containing January 4th
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, loc)
+
+	// Step back to the Monday of that week (Weekday counts from Sunday = 0)
+	daysSinceMonday := (int(jan4.Weekday()) + 6) % 7
+	firstMonday := jan4.AddDate(0, 0, -daysSinceMonday)
+
+	// Step forward to the requested week
+	return firstMonday.AddDate(0, 0, (week-1)*7)
+}
 
-	return err
+// IncludesFile checks if a file should be included in statistics, applying
+// the file filter, the ignored files and the ignore globs
+func (s *RepositoryStats) IncludesFile(filename string) bool {
+	if !shouldIncludeFile(filename, s.FileFilter, s.IgnoreFiles) {
+		return false
+	}
+	for _, pattern := range s.IgnoreGlobs {
+		if matchIgnoreGlob(pattern, filename) {
+			return false
+		}
+	}
+	return true
 }
 
-// getWeekStart returns the start date (Sunday) of the given ISO week
-func getWeekStart(year, week int) time.Time {
-	// Get the date of the first day of the year
-	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
-	
-	// Get the day of the week for January 1st
-	jan1Weekday := int(jan1.Weekday())
-	
-	// Calculate days to add to get to the first day (Monday) of week 1
-	daysToFirstMonday := (8 - jan1Weekday) % 7
-	if jan1Weekday == 0 { // Sunday
-		daysToFirstMonday = 1
-	}
-	
-	// Calculate the date of the first Monday of week 1
-	firstMonday := jan1.AddDate(0, 0, daysToFirstMonday)
-	
-	// Calculate the date of the first day (Sunday) of the requested week
-	// Week 1 starts with the Monday closest to January 1st
-	weekStart := firstMonday.AddDate(0, 0, (week-1)*7-1)
-	
-	return weekStart
+// matchIgnoreGlob reports whether a file matches a gitignore-style pattern.
+// A pattern without a slash matches a file or directory name at any depth,
+// a leading slash anchors the pattern to the repository root and a trailing
+// slash matches directories only.
+func matchIgnoreGlob(pattern, filename string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+
+	// Anchored patterns are matched from the root; the rest may start anywhere
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		pattern = "**/" + pattern
+	}
+	if matchGlob(pattern+"/*/**", filename) {
+		return true
+	}
+	return !dirOnly && matchGlob(pattern, filename)
 }
 
 // shouldIncludeFile checks if a file should be included in statistics
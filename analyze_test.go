@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalkCommitsYieldsFilteredRecords(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", start, map[string]string{"main.go": "package main\n", "README.md": "hello\n"})
+	r.commit("Bob", start.AddDate(0, 0, 1), map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	r.commit("Carol", start.AddDate(0, 0, 2), map[string]string{"README.md": "hello\nworld\n"})
+
+	stats := newTestStats()
+	stats.FileFilter = ".go"
+
+	// Collect the streamed records
+	var records []*CommitRecord
+	err := WalkCommits(r.repo, stats, func(record *CommitRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+
+	// Carol's commit only touches README.md and is filtered out
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	bob := records[0]
+	if bob.Author != "Bob" || bob.Email != "Bob@example.com" || len(bob.Hash) != 40 {
+		t.Errorf("Unexpected record for Bob's commit: %+v", bob)
+	}
+	if len(bob.Files) != 1 || bob.Files[0].Name != "main.go" || bob.Files[0].Additions != 2 {
+		t.Errorf("Unexpected file changes for Bob's commit: %+v", bob.Files)
+	}
+
+	alice := records[1]
+	if len(alice.Files) != 1 || alice.Files[0].Name != "main.go" {
+		t.Errorf("Expected only main.go in Alice's commit, got %+v", alice.Files)
+	}
+
+	// Aggregating the records gives the same stats as analyzeRepository
+	aggregated := newTestStats()
+	for _, record := range records {
+		aggregateCommit(aggregated, record)
+	}
+	analyzed := newTestStats()
+	analyzed.FileFilter = ".go"
+	if err := analyzeRepository(r.repo, analyzed); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if aggregated.TotalCommits != analyzed.TotalCommits || aggregated.TotalLines != analyzed.TotalLines {
+		t.Errorf("Aggregated totals %d/%d differ from analyzed totals %d/%d",
+			aggregated.TotalCommits, aggregated.TotalLines, analyzed.TotalCommits, analyzed.TotalLines)
+	}
+}
+
+func TestWalkCommitsCountsInitialCommitLines(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", start, map[string]string{"main.go": "package main\n\nfunc main() {}\n", "empty.go": ""})
+
+	stats := newTestStats()
+	stats.ClassifyLines = true
+	stats.ReworkWindow = 7 * 24 * time.Hour
+	var record *CommitRecord
+	if err := WalkCommits(r.repo, stats, func(c *CommitRecord) error {
+		record = c
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+
+	// The newline ending the last line does not start another one
+	changes := make(map[string]FileChange)
+	for _, change := range record.Files {
+		changes[change.Name] = change
+	}
+	source := changes["main.go"]
+	if source.Additions != 3 || source.CodeLines != 2 || source.BlankLines != 1 {
+		t.Errorf("Expected 3 added lines of which 1 blank, got %+v", source)
+	}
+	if len(source.ops) != 1 || source.ops[0].Lines != 3 {
+		t.Errorf("Expected one operation adding 3 lines, got %+v", source.ops)
+	}
+	if changes["empty.go"].Additions != 0 {
+		t.Errorf("Expected no lines in an empty file, got %d", changes["empty.go"].Additions)
+	}
+}
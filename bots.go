@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BotMode selects how commits by bots and automation accounts are handled
+type BotMode string
+
+// Supported bot modes
+const (
+	BotsInclude  BotMode = "include"  // Count bots like any other author
+	BotsExclude  BotMode = "exclude"  // Leave bot commits out of every statistic
+	BotsSeparate BotMode = "separate" // Count bots, but show them as a separate group
+)
+
+// parseBotMode parses a -bots value
+func parseBotMode(value string) (BotMode, error) {
+	switch mode := BotMode(strings.ToLower(value)); mode {
+	case BotsInclude, BotsExclude, BotsSeparate:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported bot mode %q (expected include, exclude or separate)", value)
+}
+
+// knownBots lists the lower-case names of common bots that do not carry a bot marker
+var knownBots = map[string]bool{
+	"dependabot":           true,
+	"renovate":             true,
+	"greenkeeper":          true,
+	"snyk-bot":             true,
+	"github-actions":       true,
+	"semantic-release-bot": true,
+	"pre-commit-ci":        true,
+	"allcontributors":      true,
+	"gitlab-bot":           true,
+	"travis-ci":            true,
+	"jenkins":              true,
+	"weblate":              true,
+	"imgbot":               true,
+	"codecov-io":           true,
+}
+
+// isBot reports whether an author is a bot: a built-in pattern matches the
+// name or email, or either appears in stats.BotAuthors
+func isBot(stats *RepositoryStats, name, email string) bool {
+	name, email = strings.ToLower(strings.TrimSpace(name)), strings.ToLower(strings.TrimSpace(email))
+	if stats.BotAuthors[name] || stats.BotAuthors[email] {
+		return true
+	}
+
+	// GitHub apps commit as "name[bot]" with a "[bot]@users.noreply.github.com" address
+	if strings.Contains(name, "[bot]") || strings.Contains(email, "[bot]@") {
+		return true
+	}
+	if knownBots[name] || strings.HasSuffix(name, "-bot") || strings.HasSuffix(name, " bot") {
+		return true
+	}
+
+	// Addresses such as bot@example.com, ci-bot@example.com or bot-noreply@example.com
+	local, _, _ := strings.Cut(email, "@")
+	for _, part := range strings.FieldsFunc(local, func(r rune) bool { return r == '-' || r == '_' || r == '.' || r == '+' }) {
+		if part == "bot" || part == "bots" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBotAuthors parses a comma-separated list of bot names and emails
+func parseBotAuthors(value string) map[string]bool {
+	authors := make(map[string]bool)
+	for _, author := range strings.Split(value, ",") {
+		if author = strings.ToLower(strings.TrimSpace(author)); author != "" {
+			authors[author] = true
+		}
+	}
+	return authors
+}
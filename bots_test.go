@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	stats := newTestStats()
+	stats.BotAuthors = parseBotAuthors("Release Manager, deploy@example.com")
+
+	tests := []struct {
+		name, email string
+		expected    bool
+	}{
+		{"dependabot[bot]", "49699333+dependabot[bot]@users.noreply.github.com", true},
+		{"renovate", "renovate@whitesourcesoftware.com", true},
+		{"github-actions", "41898282+github-actions@users.noreply.github.com", true},
+		{"docs-bot", "docs@example.com", true},
+		{"CI", "ci-bot@example.com", true},
+		{"Release Manager", "releases@example.com", true},
+		{"Deployer", "Deploy@Example.com", true},
+		{"Alice", "alice@example.com", false},
+		{"Abbott", "abbott@example.com", false},
+		{"Bob", "robot.bob@example.com", false},
+	}
+	for _, test := range tests {
+		if result := isBot(stats, test.name, test.email); result != test.expected {
+			t.Errorf("Expected isBot(%q, %q) to be %t", test.name, test.email, test.expected)
+		}
+	}
+}
+
+func TestParseBotMode(t *testing.T) {
+	if mode, err := parseBotMode("Separate"); err != nil || mode != BotsSeparate {
+		t.Errorf("Expected separate mode, got %q (%v)", mode, err)
+	}
+	if _, err := parseBotMode("hide"); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+}
+
+func TestBotModes(t *testing.T) {
+	repo := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.commit("Alice", when, map[string]string{"main.go": "package main\n"})
+	repo.commit("dependabot[bot]", when.Add(time.Hour), map[string]string{"go.mod": "module example\n"})
+
+	stats := newTestStats()
+	stats.Bots = BotsSeparate
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if stats.TotalCommits != 2 || !stats.Authors["dependabot[bot]"].Bot || stats.Authors["Alice"].Bot {
+		t.Errorf("Expected the bot to be counted and flagged, got %+v", stats.Authors)
+	}
+
+	stats = newTestStats()
+	stats.Bots = BotsExclude
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if _, ok := stats.Authors["dependabot[bot]"]; ok || stats.TotalCommits != 1 {
+		t.Errorf("Expected the bot to be excluded, got %+v", stats.Authors)
+	}
+}
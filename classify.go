@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strings"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// LineKind is the kind of content on a line of source code
+type LineKind int
+
+// Kinds of lines told apart by the line classifier
+const (
+	LineCode LineKind = iota
+	LineComment
+	LineBlank
+)
+
+// commentSyntax describes how comments are written in a language
+type commentSyntax struct {
+	Line       []string // Prefixes starting a comment that runs to the end of the line
+	BlockStart string   // Start of a block comment, empty when the language has none
+	BlockEnd   string
+}
+
+// Comment syntaxes shared by several languages
+var (
+	cComments     = commentSyntax{Line: []string{"//"}, BlockStart: "/*", BlockEnd: "*/"}
+	hashComments  = commentSyntax{Line: []string{"#"}}
+	sqlComments   = commentSyntax{Line: []string{"--"}, BlockStart: "/*", BlockEnd: "*/"}
+	xmlComments   = commentSyntax{BlockStart: "<!--", BlockEnd: "-->"}
+	styleComments = commentSyntax{BlockStart: "/*", BlockEnd: "*/"}
+)
+
+// commentSyntaxes maps the languages from languageFromName to their comment syntax.
+// Lines of other languages are classified as code or blank only.
+var commentSyntaxes = map[string]commentSyntax{
+	"Go":               cComments,
+	"JavaScript":       cComments,
+	"TypeScript":       cComments,
+	"Java":             cComments,
+	"Kotlin":           cComments,
+	"Scala":            cComments,
+	"Swift":            cComments,
+	"Objective-C":      cComments,
+	"C":                cComments,
+	"C++":              cComments,
+	"C#":               cComments,
+	"Rust":             cComments,
+	"PHP":              {Line: []string{"//", "#"}, BlockStart: "/*", BlockEnd: "*/"},
+	"Dart":             cComments,
+	"Groovy":           cComments,
+	"Protocol Buffers": cComments,
+	"SCSS":             cComments,
+	"Less":             cComments,
+	"CSS":              styleComments,
+	"Python":           {Line: []string{"#"}, BlockStart: `"""`, BlockEnd: `"""`},
+	"Ruby":             {Line: []string{"#"}, BlockStart: "=begin", BlockEnd: "=end"},
+	"Perl":             hashComments,
+	"Shell":            hashComments,
+	"PowerShell":       {Line: []string{"#"}, BlockStart: "<#", BlockEnd: "#>"},
+	"R":                hashComments,
+	"Elixir":           hashComments,
+	"YAML":             hashComments,
+	"TOML":             hashComments,
+	"Makefile":         hashComments,
+	"Dockerfile":       hashComments,
+	"CMake":            hashComments,
+	"Starlark":         hashComments,
+	"HCL":              {Line: []string{"#", "//"}, BlockStart: "/*", BlockEnd: "*/"},
+	"SQL":              sqlComments,
+	"Lua":              {Line: []string{"--"}, BlockStart: "--[[", BlockEnd: "]]"},
+	"Haskell":          {Line: []string{"--"}, BlockStart: "{-", BlockEnd: "-}"},
+	"Erlang":           {Line: []string{"%"}},
+	"Clojure":          {Line: []string{";"}},
+	"HTML":             xmlComments,
+	"XML":              xmlComments,
+	"Markdown":         xmlComments,
+	"Vue":              xmlComments,
+	"Svelte":           xmlComments,
+	"Go Module":        {Line: []string{"//"}},
+}
+
+// lineClassifier classifies the lines of one version of a file in order,
+// keeping track of block comments spanning several lines
+type lineClassifier struct {
+	syntax  commentSyntax
+	inBlock bool
+}
+
+// newLineClassifier creates a classifier for a file, picking the comment
+// syntax from its name
+func newLineClassifier(filename string) *lineClassifier {
+	return &lineClassifier{syntax: commentSyntaxes[languageFromName(filename)]}
+}
+
+// classify returns the kind of the next line of the file. A line holding
+// both code and a comment counts as code.
+func (c *lineClassifier) classify(line string) LineKind {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if c.inBlock {
+			return LineComment
+		}
+		return LineBlank
+	}
+
+	kind := LineComment
+	for line != "" {
+		if c.inBlock {
+			end := strings.Index(line, c.syntax.BlockEnd)
+			if end < 0 {
+				return kind
+			}
+			c.inBlock = false
+			line = strings.TrimSpace(line[end+len(c.syntax.BlockEnd):])
+			continue
+		}
+
+		if c.startsLineComment(line) {
+			return kind
+		}
+		if c.syntax.BlockStart != "" && strings.HasPrefix(line, c.syntax.BlockStart) {
+			c.inBlock = true
+			line = strings.TrimSpace(line[len(c.syntax.BlockStart):])
+			continue
+		}
+
+		// The line has code; a block comment opened after the code still
+		// affects the lines that follow
+		if c.syntax.BlockStart != "" {
+			if start := strings.LastIndex(line, c.syntax.BlockStart); start >= 0 {
+				rest := line[start+len(c.syntax.BlockStart):]
+				c.inBlock = !strings.Contains(rest, c.syntax.BlockEnd)
+			}
+		}
+		return LineCode
+	}
+	return kind
+}
+
+// startsLineComment reports whether the line starts with a line comment
+func (c *lineClassifier) startsLineComment(line string) bool {
+	for _, prefix := range c.syntax.Line {
+		if strings.HasPrefix(line, prefix) {
+			// Lua block comments start with the line comment prefix
+			if c.syntax.BlockStart != "" && strings.HasPrefix(line, c.syntax.BlockStart) {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines splits the content of a chunk into lines, dropping the empty
+// string after a trailing newline
+func splitLines(content string) []string {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// classifyChunks counts the added and deleted lines of a file change by kind.
+// The old and new versions of the file are classified separately so that
+// block comments are followed through the unchanged lines.
+func classifyChunks(change *FileChange, chunks []fdiff.Chunk) {
+	before, after := newLineClassifier(change.Name), newLineClassifier(change.Name)
+	for _, chunk := range chunks {
+		for _, line := range splitLines(chunk.Content()) {
+			switch chunk.Type() {
+			case fdiff.Equal:
+				before.classify(line)
+				after.classify(line)
+			case fdiff.Delete:
+				change.countKind(before.classify(line))
+			case fdiff.Add:
+				change.countKind(after.classify(line))
+			}
+		}
+	}
+}
+
+// classifyContent counts every line of a new file by kind
+func classifyContent(change *FileChange, content string) {
+	classifier := newLineClassifier(change.Name)
+	for _, line := range splitLines(content) {
+		change.countKind(classifier.classify(line))
+	}
+}
+
+// countKind counts a changed line of the given kind
+func (f *FileChange) countKind(kind LineKind) {
+	switch kind {
+	case LineCode:
+		f.CodeLines++
+	case LineComment:
+		f.CommentLines++
+	case LineBlank:
+		f.BlankLines++
+	}
+}
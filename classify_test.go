@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineClassifier(t *testing.T) {
+	classifier := newLineClassifier("main.go")
+	lines := []struct {
+		line     string
+		expected LineKind
+	}{
+		{"package main", LineCode},
+		{"", LineBlank},
+		{"// Comment", LineComment},
+		{"/* Block", LineComment},
+		{"", LineComment},
+		{"   still a comment */", LineComment},
+		{"x := 1 // trailing comment", LineCode},
+		{"/* short */ y := 2", LineCode},
+		{"z := 3 /* opens", LineCode},
+		{"closes */", LineComment},
+		{"\t", LineBlank},
+	}
+	for _, l := range lines {
+		if kind := classifier.classify(l.line); kind != l.expected {
+			t.Errorf("Expected %q to be %d, got %d", l.line, l.expected, kind)
+		}
+	}
+}
+
+func TestLineClassifierLanguages(t *testing.T) {
+	tests := []struct {
+		filename string
+		line     string
+		expected LineKind
+	}{
+		{"deploy.sh", "# comment", LineComment},
+		{"app.py", `"""Docstring."""`, LineComment},
+		{"schema.sql", "-- comment", LineComment},
+		{"init.lua", "--[[ block ]]", LineComment},
+		{"index.html", "<!-- comment -->", LineComment},
+		{"notes.unknownext", "# not a comment", LineCode},
+	}
+	for _, test := range tests {
+		if kind := newLineClassifier(test.filename).classify(test.line); kind != test.expected {
+			t.Errorf("Expected %q in %s to be %d, got %d", test.line, test.filename, test.expected, kind)
+		}
+	}
+}
+
+func TestWalkCommitsClassifiesLines(t *testing.T) {
+	repo := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.commit("Alice", when, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	repo.commit("Bob", when.Add(time.Hour), map[string]string{
+		"main.go": "package main\n\n// main does nothing\nfunc main() {}\n",
+	})
+	repo.commit("Alice", when.Add(2*time.Hour), map[string]string{
+		"main.go": "package main\n\n// main does nothing\nfunc main() {\n\tprintln()\n}\n",
+	})
+
+	stats := newTestStats()
+	stats.ClassifyLines = true
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+
+	// Bob only added a comment
+	if bob := stats.Authors["Bob"]; bob.LinesChanged != 1 || bob.CodeLinesChanged != 0 {
+		t.Errorf("Expected Bob to change 1 line and no code, got %+v", bob)
+	}
+	// Alice wrote 2 code lines, then replaced one with 3 more
+	if alice := stats.Authors["Alice"]; alice.CodeLinesChanged != 6 {
+		t.Errorf("Expected Alice to change 6 code lines, got %+v", alice)
+	}
+
+	// Skipping comment-only commits drops Bob entirely
+	stats = newTestStats()
+	stats.ClassifyLines = true
+	stats.SkipNonCode = true
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if _, ok := stats.Authors["Bob"]; ok || stats.TotalCommits != 2 {
+		t.Errorf("Expected Bob's commit to be skipped, got %d commits", stats.TotalCommits)
+	}
+}
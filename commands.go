@@ -0,0 +1,330 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// flagGroup selects the sets of flags a command accepts
+type flagGroup int
+
+// Sets of flags shared between commands
+const (
+	flagsFilter        flagGroup = 1 << iota // Commit and file filters accepted by every command
+	flagsAuthors                             // Author aggregation: teams, line classification, rework, components
+	flagsPeriod                              // Time series: period, week start and dense output
+	flagsWeekly                              // The -weekly switch of the original command line
+	flagsTop                                 // Number of files to list
+	flagsCoupling                            // Change coupling thresholds
+	flagsKnowledgeLoss                       // Departed authors
+	flagsRepos                               // Repository manifest for combined statistics
+
+	flagsAll = flagsFilter | flagsAuthors | flagsPeriod | flagsWeekly | flagsTop | flagsCoupling | flagsKnowledgeLoss | flagsRepos
+)
+
+// command is a gitstics subcommand
+type command struct {
+	Name    string
+	Summary string
+	Flags   flagGroup
+	Report  bool // Listed under "gitstics report"
+	Combine bool // Accepts several repositories and combines their statistics
+	Ranges  int  // Number of ranges to compare, given before the repository
+}
+
+// commands lists the subcommands in the order they are shown in the help
+var commands = []*command{
+	{Name: "authors", Summary: "Show commits and lines changed per author (the default)", Flags: flagsFilter | flagsAuthors | flagsRepos, Combine: true},
+	{Name: "weekly", Summary: "Show lines changed per author over time, per week unless -period says otherwise", Flags: flagsFilter | flagsAuthors | flagsPeriod | flagsRepos, Combine: true},
+	{Name: "files", Summary: "Show commits, lines changed and authors per file", Flags: flagsFilter | flagsTop},
+	{Name: "compare", Summary: "Compare commits and lines changed per author between two date or revision ranges", Flags: flagsFilter, Ranges: 2},
+	{Name: "ownership", Summary: "Show who owns the code at HEAD, from blame", Flags: flagsFilter, Report: true},
+	{Name: "truckfactor", Summary: "Compute how many authors could leave before half the files have no owner", Flags: flagsFilter, Report: true},
+	{Name: "knowledgeloss", Summary: "Find code mostly written by authors who have left", Flags: flagsFilter | flagsKnowledgeLoss, Report: true},
+	{Name: "coupling", Summary: "Find files that are frequently changed in the same commit", Flags: flagsFilter | flagsCoupling, Report: true},
+	{Name: "hotspots", Summary: "Rank files that are both complex and frequently changed", Flags: flagsFilter | flagsTop, Report: true},
+	{Name: "age", Summary: "Show how old the code at HEAD is and how much of each month's work survives", Flags: flagsFilter, Report: true},
+}
+
+// legacyCommand accepts the flags of every command, as the command line did
+// before subcommands, and shows author statistics
+var legacyCommand = &command{Name: "authors", Flags: flagsAll, Combine: true}
+
+// configShowCommand prints the effective configuration for any flags
+var configShowCommand = &command{Name: "config show", Summary: "Print the configuration in effect: .gitstics.yaml files merged with the flags", Flags: flagsAll}
+
+// findCommand returns the command with the given name, or nil
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// options holds the values of all command-line flags. Flags a command does
+// not accept keep their defaults.
+type options struct {
+	Ignore            string
+	Ext               string
+	Since             string
+	Until             string
+	Timezone          string
+	Bots              string
+	BotAuthors        string
+	Merges            string
+	IgnoreRevs        string
+	IgnoreWhitespace  bool
+	Format            string
+	Weekly            bool
+	Period            string
+	WeekStart         string
+	Dense             bool
+	Teams             string
+	By                string
+	TagOrder          string
+	CodeLines         bool
+	SkipNonCode       bool
+	Rework            bool
+	ReworkWindow      int
+	GroupBy           string
+	Components        string
+	Top               int
+	MinRevisions      int
+	MaxCommitFiles    int
+	Departed          string
+	InactiveMonths    int
+	Repos             string
+	RecurseSubmodules bool
+}
+
+// defaultOptions returns the flag defaults
+func defaultOptions() *options {
+	return &options{
+		Bots:           string(BotsInclude),
+		Merges:         "include",
+		Format:         FormatTable,
+		WeekStart:      string(WeekStartMonday),
+		By:             "author",
+		TagOrder:       string(TagOrderSemver),
+		ReworkWindow:   int(DefaultReworkWindow.Hours() / 24),
+		Top:            20,
+		MinRevisions:   5,
+		MaxCommitFiles: 50,
+		InactiveMonths: 6,
+	}
+}
+
+// newFlagSet creates the flag set of a command, storing the values in opts
+func newFlagSet(name string, groups flagGroup, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	if groups&flagsFilter != 0 {
+		fs.StringVar(&opts.Ext, "ext", opts.Ext, "File extension filter (e.g., .js, .go)")
+		fs.StringVar(&opts.Ignore, "ignore", opts.Ignore, "Comma-separated list of additional files to ignore")
+		fs.StringVar(&opts.Since, "since", opts.Since, "Only analyze commits authored on or after this date (YYYY-MM-DD)")
+		fs.StringVar(&opts.Until, "until", opts.Until, "Only analyze commits authored on or before this date (YYYY-MM-DD)")
+		fs.StringVar(&opts.Timezone, "tz", opts.Timezone, "Time zone used to bucket commits (e.g. UTC, Local, Europe/Stockholm); defaults to each commit's own offset")
+		fs.StringVar(&opts.Bots, "bots", opts.Bots, "How to handle commits by bots and automation accounts (include, exclude, separate)")
+		fs.StringVar(&opts.BotAuthors, "bot-authors", opts.BotAuthors, "Comma-separated list of additional bot names or emails")
+		fs.StringVar(&opts.Merges, "merges", opts.Merges, "Merge commit policy (include, exclude)")
+		fs.StringVar(&opts.IgnoreRevs, "ignore-revs", opts.IgnoreRevs, "File listing commits to leave out, one hash per line (default: the repository's "+DefaultIgnoreRevsFile+")")
+		fs.BoolVar(&opts.IgnoreWhitespace, "ignore-whitespace", opts.IgnoreWhitespace, "Ignore whitespace and line-ending changes when counting changed lines")
+		fs.StringVar(&opts.Format, "format", opts.Format, "Output format (table, json, csv, ndjson)")
+	}
+	if groups&flagsWeekly != 0 {
+		fs.BoolVar(&opts.Weekly, "weekly", opts.Weekly, "Show weekly code frequency statistics")
+	}
+	if groups&flagsPeriod != 0 {
+		fs.StringVar(&opts.Period, "period", opts.Period, "Show time series statistics per period (day, week, month, quarter, year)")
+		fs.StringVar(&opts.WeekStart, "week-start", opts.WeekStart, "First day of the week for weekly statistics (monday, sunday)")
+		fs.BoolVar(&opts.Dense, "dense", opts.Dense, "Include periods without commits in time series output")
+	}
+	if groups&flagsAuthors != 0 {
+		fs.StringVar(&opts.Teams, "teams", opts.Teams, "File mapping author names or emails to teams (lines of: identity = team)")
+		fs.StringVar(&opts.By, "by", opts.By, "Aggregate statistics by author, team (requires -teams) or tag, per release between consecutive tags")
+		fs.StringVar(&opts.TagOrder, "tag-order", opts.TagOrder, "Order of the releases for -by=tag (semver, date)")
+		fs.BoolVar(&opts.CodeLines, "code-lines", opts.CodeLines, "Classify changed lines as code, comment or blank and show code lines changed")
+		fs.BoolVar(&opts.SkipNonCode, "skip-noncode", opts.SkipNonCode, "Skip commits that only change comments and blank lines (implies -code-lines)")
+		fs.BoolVar(&opts.Rework, "rework", opts.Rework, "Track lines that are changed again shortly after being added")
+		fs.IntVar(&opts.ReworkWindow, "rework-window", opts.ReworkWindow, "Rework: days within which a changed line counts as rework")
+		fs.StringVar(&opts.GroupBy, "group-by", opts.GroupBy, "Break statistics down by component: dir:N groups files by their first N directories, language by programming language")
+		fs.StringVar(&opts.Components, "components", opts.Components, "Break statistics down by the components in this file (lines of: glob component)")
+	}
+	if groups&flagsTop != 0 {
+		fs.IntVar(&opts.Top, "top", opts.Top, "Number of files to list (0 = all)")
+	}
+	if groups&flagsCoupling != 0 {
+		fs.IntVar(&opts.MinRevisions, "min-revs", opts.MinRevisions, "Coupling: minimum number of commits a file must appear in")
+		fs.IntVar(&opts.MaxCommitFiles, "max-commit-files", opts.MaxCommitFiles, "Coupling: skip commits touching more files than this (0 = no limit)")
+	}
+	if groups&flagsRepos != 0 {
+		fs.StringVar(&opts.Repos, "repos", opts.Repos, "File listing repositories to combine, one path or glob per line")
+		fs.BoolVar(&opts.RecurseSubmodules, "recurse-submodules", opts.RecurseSubmodules, "Also analyze the history of each submodule over the superproject's time window, in a column per submodule")
+	}
+	if groups&flagsKnowledgeLoss != 0 {
+		fs.StringVar(&opts.Departed, "departed", opts.Departed, "Knowledge loss: comma-separated list of authors who have left")
+		fs.IntVar(&opts.InactiveMonths, "inactive-months", opts.InactiveMonths, "Knowledge loss: treat authors without commits for this many months as departed (0 = only -departed)")
+	}
+	return fs
+}
+
+// invocation is a parsed command line
+type invocation struct {
+	Command    *command
+	Options    *options
+	Flags      *flag.FlagSet
+	RepoPath   string   // The repository, or the first of RepoPaths
+	RepoPaths  []string // Repositories or globs given as arguments
+	Ranges     []string // Ranges to compare, for commands taking ranges
+	ShowConfig bool     // "config show" prints the effective configuration
+}
+
+// parseCommandLine selects the command and parses its flags and arguments.
+// A command line that does not start with a command is parsed the way it was
+// before subcommands: every flag is accepted, the arguments are repositories
+// and an extension may follow the repository.
+func parseCommandLine(arguments []string) (*invocation, error) {
+	inv := &invocation{Options: defaultOptions(), RepoPath: "."}
+	name := ""
+	if len(arguments) > 0 {
+		name = arguments[0]
+	}
+
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		if len(arguments) > 1 {
+			cmd := findCommand(arguments[1])
+			if cmd == nil {
+				return nil, fmt.Errorf("unknown command %q", arguments[1])
+			}
+			fs := newFlagSet(cmd.Name, cmd.Flags, inv.Options)
+			fs.SetOutput(os.Stdout)
+			commandUsage(fs, cmd)()
+		} else {
+			usage(os.Stdout)
+		}
+		os.Exit(0)
+
+	case name == "report":
+		if len(arguments) < 2 || findCommand(arguments[1]) == nil || !findCommand(arguments[1]).Report {
+			reportUsage(os.Stderr)
+			os.Exit(2)
+		}
+		return inv.parse(findCommand(arguments[1]), arguments[2:])
+
+	case name == "config":
+		if len(arguments) < 2 || arguments[1] != "show" {
+			return nil, fmt.Errorf("usage: gitstics config show [flags] [repository]")
+		}
+		inv.ShowConfig = true
+		return inv.parse(configShowCommand, arguments[2:])
+
+	case findCommand(name) != nil:
+		return inv.parse(findCommand(name), arguments[1:])
+	}
+
+	// The original command line
+	inv.Command = legacyCommand
+	inv.Flags = newFlagSet("gitstics", legacyCommand.Flags, inv.Options)
+	inv.Flags.Usage = func() { usage(inv.Flags.Output()) }
+	inv.Flags.Parse(arguments)
+	args := inv.Flags.Args()
+	if len(args) > 0 {
+		if isExtension(args[0]) {
+			inv.Flags.Set("ext", args[0])
+		} else {
+			// An extension may follow the repositories
+			if len(args) > 1 && isExtension(args[len(args)-1]) {
+				inv.Flags.Set("ext", args[len(args)-1])
+				args = args[:len(args)-1]
+			}
+			inv.setRepositories(args)
+		}
+	}
+	if len(inv.RepoPaths) > 1 && !inv.Command.Combine {
+		return nil, fmt.Errorf("the %s report takes one repository", inv.Command.Name)
+	}
+	return inv, nil
+}
+
+// parse parses the flags and the optional repository of a subcommand
+func (inv *invocation) parse(cmd *command, arguments []string) (*invocation, error) {
+	inv.Command = cmd
+	inv.Flags = newFlagSet("gitstics "+cmd.Name, cmd.Flags, inv.Options)
+	inv.Flags.Usage = commandUsage(inv.Flags, cmd)
+	inv.Flags.Parse(arguments)
+
+	args := inv.Flags.Args()
+	if len(args) < cmd.Ranges {
+		return nil, fmt.Errorf("gitstics %s takes %d ranges before the repository", cmd.Name, cmd.Ranges)
+	}
+	inv.Ranges, args = args[:cmd.Ranges], args[cmd.Ranges:]
+	if len(args) > 1 && !cmd.Combine {
+		return nil, fmt.Errorf("unexpected argument %q (gitstics %s takes one repository; use -ext for extensions)", args[1], cmd.Name)
+	}
+	inv.setRepositories(args)
+	return inv, nil
+}
+
+// setRepositories records the repository arguments
+func (inv *invocation) setRepositories(args []string) {
+	inv.RepoPaths = args
+	if len(args) > 0 {
+		inv.RepoPath = args[0]
+	}
+}
+
+// isExtension reports whether a positional argument of the original command
+// line is a file extension rather than a repository path. Paths such as ".",
+// "./repo" or "../repo" and existing directories are repositories.
+func isExtension(arg string) bool {
+	if len(arg) < 2 || !strings.HasPrefix(arg, ".") || arg == ".." || strings.ContainsAny(arg, `/\`) {
+		return false
+	}
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		return false
+	}
+	return true
+}
+
+// usage prints the list of commands
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gitstics <command> [flags] [repository]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "  %-14s %s\n", "report", "Run one of the reports: gitstics report <name>")
+	fmt.Fprintf(w, "  %-14s %s\n", "config show", "Print the effective configuration")
+	fmt.Fprintf(w, "  %-14s %s\n", "help", "Show the flags of a command: gitstics help <command>")
+	fmt.Fprintf(w, "\nWithout a command, gitstics accepts the flags of every command and shows\nauthor statistics, as in: gitstics [flags] [repository] [extension]\n")
+}
+
+// reportUsage prints the list of reports
+func reportUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gitstics report <name> [flags] [repository]\n\nReports:\n")
+	for _, cmd := range commands {
+		if cmd.Report {
+			fmt.Fprintf(w, "  %-14s %s\n", cmd.Name, cmd.Summary)
+		}
+	}
+}
+
+// commandUsage returns the help function of a command
+func commandUsage(fs *flag.FlagSet, cmd *command) func() {
+	return func() {
+		repositories := "[repository]"
+		if cmd.Combine {
+			repositories = "[repository...]"
+		}
+		if cmd.Ranges == 2 {
+			repositories = "<before> <after> " + repositories
+		}
+		fmt.Fprintf(fs.Output(), "Usage: gitstics %s [flags] %s\n\n%s\n\nFlags:\n", cmd.Name, repositories, cmd.Summary)
+		fs.PrintDefaults()
+	}
+}
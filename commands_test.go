@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		arguments []string
+		command   string
+		repoPath  string
+		ext       string
+		format    string
+	}{
+		{[]string{}, "authors", ".", "", FormatTable},
+		{[]string{"."}, "authors", ".", "", FormatTable},
+		{[]string{"./repo"}, "authors", "./repo", "", FormatTable},
+		{[]string{".js"}, "authors", ".", ".js", FormatTable},
+		{[]string{"-format", "json", "/path/to/repo", ".go"}, "authors", "/path/to/repo", ".go", FormatJSON},
+		{[]string{"-format", "csv", "ownership"}, "authors", "ownership", "", FormatCSV},
+		{[]string{"-ext", ".go", "age", "coupling"}, "authors", "age", ".go", FormatTable},
+		{[]string{"authors", "-ext", ".go", "."}, "authors", ".", ".go", FormatTable},
+		{[]string{"weekly", "-period", "month", "/repo"}, "weekly", "/repo", "", FormatTable},
+		{[]string{"files", "-top", "5"}, "files", ".", "", FormatTable},
+		{[]string{"report", "coupling", "-min-revs", "2", "/repo"}, "coupling", "/repo", "", FormatTable},
+	}
+	for _, test := range tests {
+		inv, err := parseCommandLine(test.arguments)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.arguments, err)
+			continue
+		}
+		if inv.Command.Name != test.command || inv.RepoPath != test.repoPath || inv.Options.Ext != test.ext || inv.Options.Format != test.format {
+			t.Errorf("%v: expected %s on %q with ext %q and format %s, got %s on %q with ext %q and format %s",
+				test.arguments, test.command, test.repoPath, test.ext, test.format,
+				inv.Command.Name, inv.RepoPath, inv.Options.Ext, inv.Options.Format)
+		}
+	}
+
+	// Each command only accepts its own flags
+	inv, err := parseCommandLine([]string{"report", "coupling", "-min-revs", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Options.MinRevisions != 2 || inv.Flags.Lookup("top") != nil || inv.Flags.Lookup("min-revs") == nil {
+		t.Errorf("Expected the coupling flags only, got min-revs %d", inv.Options.MinRevisions)
+	}
+
+	if _, err := parseCommandLine([]string{"ownership", "/repo", ".go"}); err == nil {
+		t.Errorf("Expected an error for a second argument to a report")
+	}
+
+	// Author statistics combine several repositories
+	inv, err = parseCommandLine([]string{"/api", "/web", ".go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.RepoPaths) != 2 || inv.RepoPath != "/api" || inv.Options.Ext != ".go" {
+		t.Errorf("Expected two repositories and an extension, got %v and %q", inv.RepoPaths, inv.Options.Ext)
+	}
+	if inv, err = parseCommandLine([]string{"weekly", "-repos", "repos.txt", "/api", "/web"}); err != nil || len(inv.RepoPaths) != 2 || inv.Options.Repos != "repos.txt" {
+		t.Errorf("Expected weekly to accept several repositories and a manifest, got %v", err)
+	}
+	if inv, err := parseCommandLine([]string{"config", "show", "-format", "json"}); err != nil || !inv.ShowConfig {
+		t.Errorf("Expected config show to be recognized, got %v", err)
+	}
+
+	// Compare takes two ranges before the repository
+	if inv, err = parseCommandLine([]string{"compare", "v1.1..v1.2", "v1.2..v1.3", "/repo"}); err != nil || len(inv.Ranges) != 2 || inv.Ranges[1] != "v1.2..v1.3" || inv.RepoPath != "/repo" {
+		t.Errorf("Expected two ranges and a repository, got %v", err)
+	}
+	if _, err := parseCommandLine([]string{"compare", "2024-Q1"}); err == nil {
+		t.Errorf("Expected an error for a single range")
+	}
+}
+
+func TestIsExtension(t *testing.T) {
+	dir := t.TempDir()
+	hidden := filepath.Join(dir, ".repo")
+	if err := os.Mkdir(hidden, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := map[string]bool{
+		".go":     true,
+		".js":     true,
+		".":       false,
+		"..":      false,
+		"./repo":  false,
+		"../repo": false,
+		".repo":   false,
+		"repo":    false,
+	}
+	for arg, expected := range tests {
+		if isExtension(arg) != expected {
+			t.Errorf("isExtension(%q) = %v, expected %v", arg, !expected, expected)
+		}
+	}
+}
+
+func TestFileRecords(t *testing.T) {
+	stats := newTestStats()
+	stats.Files = map[string]*FileStats{
+		"a.go": {Name: "a.go", CommitCount: 3, LinesAdded: 10, LinesDeleted: 2, Authors: map[string]int{"alice": 2, "bob": 1}},
+		"b.go": {Name: "b.go", CommitCount: 1, LinesAdded: 20, Authors: map[string]int{"bob": 1, "alice": 1}},
+		"c.go": {Name: "c.go", CommitCount: 1, LinesAdded: 1, Authors: map[string]int{"carol": 1}},
+	}
+
+	records := fileRecords(stats, 2)
+	if len(records) != 2 || records[0].Path != "b.go" || records[1].Path != "a.go" {
+		t.Fatalf("Expected b.go and a.go by lines changed, got %+v", records)
+	}
+	if records[1].LinesChanged != 12 || records[1].Authors != 2 || records[1].MainAuthor != "alice" {
+		t.Errorf("Unexpected record for a.go: %+v", records[1])
+	}
+	if records[0].MainAuthor != "alice" {
+		t.Errorf("Expected ties between authors broken by name, got %s", records[0].MainAuthor)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Status of an author in a comparison
+const (
+	StatusNew        = "new"        // Commits in the second range only
+	StatusDeparted   = "departed"   // Commits in the first range only
+	StatusContinuing = "continuing" // Commits in both ranges
+)
+
+// comparisonRange is one side of a comparison: a date window, a revision
+// range, or the history up to a revision
+type comparisonRange struct {
+	Label        string
+	Since, Until time.Time // Date window, Until exclusive
+	Revision     string    // Last revision of the range, HEAD when empty
+	BaseRevision string    // Revision whose history is left out
+}
+
+// parseComparisonRange parses one side of a comparison. Dates are written as
+// YYYY, YYYY-MM, YYYY-Qn or YYYY-MM-DD; a single date covers the whole year,
+// month, quarter or day and two dates joined by ".." cover everything from the
+// start of the first to the end of the second, with either end left open.
+// Anything else is a revision range "A..B", the commits in the history of B
+// but not of A, or a single revision, its whole history. Dates take
+// precedence over revisions: a revision that looks like a date, such as the
+// tag 2024, is written rev:2024, or tag:2024 to only match tags.
+func parseComparisonRange(spec string, loc *time.Location) (comparisonRange, error) {
+	r := comparisonRange{Label: spec}
+	if spec == "" || spec == ".." {
+		return r, fmt.Errorf("empty range %q", spec)
+	}
+
+	from, to, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		to = from
+	}
+
+	// A prefix on either side makes the range a revision range
+	from, fromRevision := revisionPrefix(from)
+	to, toRevision := revisionPrefix(to)
+	if fromRevision || toRevision {
+		if from == "" && to == "" {
+			return r, fmt.Errorf("empty range %q", spec)
+		}
+		r.BaseRevision, r.Revision = from, to
+		if !isRange {
+			r.BaseRevision = ""
+		}
+		return r, nil
+	}
+
+	fromStart, _, fromDate := parseDateSpan(from, loc)
+	_, toEnd, toDate := parseDateSpan(to, loc)
+	if (fromDate || from == "") && (toDate || to == "") {
+		r.Since, r.Until = fromStart, toEnd
+		if !r.Until.IsZero() && !r.Since.IsZero() && !r.Until.After(r.Since) {
+			return r, fmt.Errorf("range %q ends before it starts", spec)
+		}
+		return r, nil
+	}
+
+	if isRange {
+		r.BaseRevision, r.Revision = from, to
+	} else {
+		r.Revision = spec
+	}
+	return r, nil
+}
+
+// revisionPrefix strips a rev: or tag: prefix from a revision, turning tag:
+// into the full name of the tag so that branches of the same name are not
+// matched. ok reports whether there was a prefix.
+func revisionPrefix(value string) (revision string, ok bool) {
+	if name, ok := strings.CutPrefix(value, "tag:"); ok {
+		if name == "" {
+			return "", true
+		}
+		return "refs/tags/" + name, true
+	}
+	return strings.CutPrefix(value, "rev:")
+}
+
+// parseDateSpan parses a year, month, quarter or day and returns its start
+// and exclusive end
+func parseDateSpan(value string, loc *time.Location) (start, end time.Time, ok bool) {
+	if loc == nil {
+		loc = time.Local
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, t.AddDate(0, 0, 1), true
+	}
+	if t, err := time.ParseInLocation("2006-01", value, loc); err == nil {
+		return t, t.AddDate(0, 1, 0), true
+	}
+	if len(value) == 7 && (value[4:6] == "-Q" || value[4:6] == "-q") {
+		year, err := strconv.Atoi(value[:4])
+		quarter := int(value[6] - '0')
+		if err == nil && quarter >= 1 && quarter <= 4 {
+			t := time.Date(year, time.Month(3*quarter-2), 1, 0, 0, 0, 0, loc)
+			return t, t.AddDate(0, 3, 0), true
+		}
+	}
+	if len(value) == 4 {
+		if year, err := strconv.Atoi(value); err == nil && year > 0 {
+			t := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+			return t, t.AddDate(1, 0, 0), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// apply restricts statistics to the range. A date window replaces the date
+// range of the settings; a revision range keeps it.
+func (r comparisonRange) apply(stats *RepositoryStats) {
+	if r.Revision == "" && r.BaseRevision == "" {
+		stats.Since, stats.Until = r.Since, r.Until
+		return
+	}
+	stats.Revision, stats.BaseRevision = r.Revision, r.BaseRevision
+}
+
+// AuthorDelta holds the change in an author's contributions between two ranges
+type AuthorDelta struct {
+	Name          string
+	Status        string // StatusNew, StatusDeparted or StatusContinuing
+	CommitsBefore int
+	CommitsAfter  int
+	LinesBefore   int
+	LinesAfter    int
+}
+
+// CommitsChange returns the change in commits
+func (d *AuthorDelta) CommitsChange() int {
+	return d.CommitsAfter - d.CommitsBefore
+}
+
+// LinesChange returns the change in lines changed
+func (d *AuthorDelta) LinesChange() int {
+	return d.LinesAfter - d.LinesBefore
+}
+
+// Comparison holds the author statistics of two ranges side by side
+type Comparison struct {
+	Before, After string         // Range labels
+	Authors       []*AuthorDelta // Sorted by lines changed in the second range, then the first
+	Total         AuthorDelta
+}
+
+// NewContributors returns the authors with commits in the second range only
+func (c *Comparison) NewContributors() []string {
+	return c.authorsWithStatus(StatusNew)
+}
+
+// DepartedContributors returns the authors with commits in the first range only
+func (c *Comparison) DepartedContributors() []string {
+	return c.authorsWithStatus(StatusDeparted)
+}
+
+// authorsWithStatus returns the names of the authors with a status, sorted
+func (c *Comparison) authorsWithStatus(status string) []string {
+	names := []string{}
+	for _, author := range c.Authors {
+		if author.Status == status {
+			names = append(names, author.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompareRanges analyzes two ranges of a repository with the same settings
+// and compares their author statistics
+func CompareRanges(repo *git.Repository, settings *RepositoryStats, before, after comparisonRange) (*Comparison, error) {
+	var sides [2]*RepositoryStats
+	for i, r := range []comparisonRange{before, after} {
+		stats := newRepositoryStats(settings)
+		r.apply(stats)
+		if err := analyzeRepository(repo, stats); err != nil {
+			return nil, fmt.Errorf("analyzing %s: %w", r.Label, err)
+		}
+		sides[i] = stats
+	}
+	return compareStats(before.Label, sides[0], after.Label, sides[1]), nil
+}
+
+// compareStats compares the author statistics of two ranges
+func compareStats(beforeLabel string, before *RepositoryStats, afterLabel string, after *RepositoryStats) *Comparison {
+	comparison := &Comparison{
+		Before: beforeLabel,
+		After:  afterLabel,
+		Total: AuthorDelta{
+			Name:          "TOTAL",
+			CommitsBefore: before.TotalCommits,
+			CommitsAfter:  after.TotalCommits,
+			LinesBefore:   before.TotalLines,
+			LinesAfter:    after.TotalLines,
+		},
+	}
+
+	deltas := make(map[string]*AuthorDelta)
+	delta := func(name string) *AuthorDelta {
+		if deltas[name] == nil {
+			deltas[name] = &AuthorDelta{Name: name}
+			comparison.Authors = append(comparison.Authors, deltas[name])
+		}
+		return deltas[name]
+	}
+	for name, author := range before.Authors {
+		d := delta(name)
+		d.CommitsBefore, d.LinesBefore = author.CommitCount, author.LinesChanged
+	}
+	for name, author := range after.Authors {
+		d := delta(name)
+		d.CommitsAfter, d.LinesAfter = author.CommitCount, author.LinesChanged
+	}
+
+	for _, d := range comparison.Authors {
+		switch {
+		case d.CommitsBefore == 0:
+			d.Status = StatusNew
+		case d.CommitsAfter == 0:
+			d.Status = StatusDeparted
+		default:
+			d.Status = StatusContinuing
+		}
+	}
+	sort.Slice(comparison.Authors, func(i, j int) bool {
+		a, b := comparison.Authors[i], comparison.Authors[j]
+		if a.LinesAfter != b.LinesAfter {
+			return a.LinesAfter > b.LinesAfter
+		}
+		if a.LinesBefore != b.LinesBefore {
+			return a.LinesBefore > b.LinesBefore
+		}
+		return a.Name < b.Name
+	})
+	return comparison
+}
+
+// percentChange returns the change from before to after in percent, or false
+// when there is nothing to compare against
+func percentChange(before, after int) (float64, bool) {
+	if before == 0 {
+		return 0, false
+	}
+	return float64(after-before) / float64(before) * 100, true
+}
+
+// formatChange formats a change with its sign
+func formatChange(change int) string {
+	return fmt.Sprintf("%+d", change)
+}
+
+// formatPercentChange formats a percentage change with its sign, or returns
+// empty when there is nothing to compare against
+func formatPercentChange(before, after int) string {
+	percent, ok := percentChange(before, after)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", percent)
+}
+
+// formatPercent formats an optional percentage for CSV output
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", *percent)
+}
+
+// deltaRecord is the machine-readable form of an AuthorDelta
+type deltaRecord struct {
+	Author               string   `json:"author,omitempty"`
+	Status               string   `json:"status,omitempty"`
+	CommitsBefore        int      `json:"commits_before"`
+	CommitsAfter         int      `json:"commits_after"`
+	CommitsChange        int      `json:"commits_change"`
+	CommitsChangePercent *float64 `json:"commits_change_percent"` // Null without commits before
+	LinesBefore          int      `json:"lines_changed_before"`
+	LinesAfter           int      `json:"lines_changed_after"`
+	LinesChange          int      `json:"lines_changed_change"`
+	LinesChangePercent   *float64 `json:"lines_changed_change_percent"` // Null without lines before
+}
+
+// newDeltaRecord converts an AuthorDelta for JSON output
+func newDeltaRecord(d *AuthorDelta) deltaRecord {
+	record := deltaRecord{
+		Author:        d.Name,
+		Status:        d.Status,
+		CommitsBefore: d.CommitsBefore,
+		CommitsAfter:  d.CommitsAfter,
+		CommitsChange: d.CommitsChange(),
+		LinesBefore:   d.LinesBefore,
+		LinesAfter:    d.LinesAfter,
+		LinesChange:   d.LinesChange(),
+	}
+	if percent, ok := percentChange(d.CommitsBefore, d.CommitsAfter); ok {
+		record.CommitsChangePercent = &percent
+	}
+	if percent, ok := percentChange(d.LinesBefore, d.LinesAfter); ok {
+		record.LinesChangePercent = &percent
+	}
+	return record
+}
+
+// deltaRow returns the table and CSV columns of an AuthorDelta
+func deltaRow(d *AuthorDelta) []string {
+	return []string{
+		d.Name,
+		d.Status,
+		fmt.Sprintf("%d", d.CommitsBefore),
+		fmt.Sprintf("%d", d.CommitsAfter),
+		formatChange(d.CommitsChange()),
+		formatPercentChange(d.CommitsBefore, d.CommitsAfter),
+		fmt.Sprintf("%d", d.LinesBefore),
+		fmt.Sprintf("%d", d.LinesAfter),
+		formatChange(d.LinesChange()),
+		formatPercentChange(d.LinesBefore, d.LinesAfter),
+	}
+}
+
+// writeComparison writes a comparison in the requested format
+func writeComparison(comparison *Comparison, format string) error {
+	switch format {
+	case FormatNDJSON:
+		return fmt.Errorf("the compare command does not support ndjson output")
+	case FormatJSON:
+		authors := []deltaRecord{}
+		for _, d := range comparison.Authors {
+			authors = append(authors, newDeltaRecord(d))
+		}
+		total := comparison.Total
+		total.Name = ""
+		return writeJSON(struct {
+			Before   string        `json:"before"`
+			After    string        `json:"after"`
+			Total    deltaRecord   `json:"total"`
+			Authors  []deltaRecord `json:"authors"`
+			New      []string      `json:"new_contributors"`
+			Departed []string      `json:"departed_contributors"`
+		}{comparison.Before, comparison.After, newDeltaRecord(&total), authors, comparison.NewContributors(), comparison.DepartedContributors()})
+	case FormatCSV:
+		rows := [][]string{{"author", "status", "commits_before", "commits_after", "commits_change", "commits_change_percent",
+			"lines_changed_before", "lines_changed_after", "lines_changed_change", "lines_changed_change_percent"}}
+		for _, d := range append(comparison.Authors, &comparison.Total) {
+			r := newDeltaRecord(d)
+			rows = append(rows, []string{
+				r.Author,
+				r.Status,
+				fmt.Sprintf("%d", r.CommitsBefore),
+				fmt.Sprintf("%d", r.CommitsAfter),
+				fmt.Sprintf("%d", r.CommitsChange),
+				formatPercent(r.CommitsChangePercent),
+				fmt.Sprintf("%d", r.LinesBefore),
+				fmt.Sprintf("%d", r.LinesAfter),
+				fmt.Sprintf("%d", r.LinesChange),
+				formatPercent(r.LinesChangePercent),
+			})
+		}
+		return writeCSV(rows)
+	default:
+		displayComparison(comparison)
+		return nil
+	}
+}
+
+// displayComparison displays a comparison in an ASCII table, followed by the
+// new and departed contributors
+func displayComparison(comparison *Comparison) {
+	fmt.Printf("Before: %s\nAfter:  %s\n", comparison.Before, comparison.After)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{"Author", "Status", "Commits Before", "Commits After", "Commits Change", "Commits Change %",
+		"Lines Before", "Lines After", "Lines Change", "Lines Change %"}
+	table.SetHeader(header)
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, d := range comparison.Authors {
+		table.Append(deltaRow(d))
+	}
+	table.Append(deltaRow(&comparison.Total))
+	table.Render()
+
+	if names := comparison.NewContributors(); len(names) > 0 {
+		fmt.Printf("New contributors: %s\n", strings.Join(names, ", "))
+	}
+	if names := comparison.DepartedContributors(); len(names) > 0 {
+		fmt.Printf("Departed contributors: %s\n", strings.Join(names, ", "))
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseComparisonRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec         string
+		since, until time.Time
+		base, rev    string
+	}{
+		{"2024-Q2", day(2024, 4, 1), day(2024, 7, 1), "", ""},
+		{"2024", day(2024, 1, 1), day(2025, 1, 1), "", ""},
+		{"2024-03", day(2024, 3, 1), day(2024, 4, 1), "", ""},
+		{"2024-01-15..2024-02", day(2024, 1, 15), day(2024, 3, 1), "", ""},
+		{"2024-06..", day(2024, 6, 1), time.Time{}, "", ""},
+		{"v1.2..v1.3", time.Time{}, time.Time{}, "v1.2", "v1.3"},
+		{"v1.2..", time.Time{}, time.Time{}, "v1.2", ""},
+		{"v1.3", time.Time{}, time.Time{}, "", "v1.3"},
+		{"rev:2024", time.Time{}, time.Time{}, "", "2024"},
+		{"tag:2024-03..rev:main", time.Time{}, time.Time{}, "refs/tags/2024-03", "main"},
+		{"tag:2024..", time.Time{}, time.Time{}, "refs/tags/2024", ""},
+	}
+	for _, test := range tests {
+		r, err := parseComparisonRange(test.spec, time.UTC)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.spec, err)
+			continue
+		}
+		if !r.Since.Equal(test.since) || !r.Until.Equal(test.until) || r.BaseRevision != test.base || r.Revision != test.rev {
+			t.Errorf("%s: expected %v to %v and %q..%q, got %v to %v and %q..%q", test.spec,
+				test.since, test.until, test.base, test.rev, r.Since, r.Until, r.BaseRevision, r.Revision)
+		}
+	}
+
+	for _, spec := range []string{"", "..", "2024-05..2024-02", "rev:", "tag:..rev:"} {
+		if _, err := parseComparisonRange(spec, time.UTC); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestCompareStats(t *testing.T) {
+	before := newTestStats()
+	before.Authors["alice"] = &AuthorStats{Name: "alice", CommitCount: 4, LinesChanged: 100}
+	before.Authors["bob"] = &AuthorStats{Name: "bob", CommitCount: 2, LinesChanged: 10}
+	before.TotalCommits, before.TotalLines = 6, 110
+	after := newTestStats()
+	after.Authors["alice"] = &AuthorStats{Name: "alice", CommitCount: 5, LinesChanged: 50}
+	after.Authors["carol"] = &AuthorStats{Name: "carol", CommitCount: 1, LinesChanged: 70}
+	after.TotalCommits, after.TotalLines = 6, 120
+
+	comparison := compareStats("2024-Q1", before, "2024-Q2", after)
+	if len(comparison.Authors) != 3 {
+		t.Fatalf("Expected 3 authors, got %d", len(comparison.Authors))
+	}
+	carol, alice, bob := comparison.Authors[0], comparison.Authors[1], comparison.Authors[2]
+	if carol.Name != "carol" || alice.Name != "alice" || bob.Name != "bob" {
+		t.Fatalf("Expected authors sorted by lines after, got %s, %s, %s", carol.Name, alice.Name, bob.Name)
+	}
+	if carol.Status != StatusNew || alice.Status != StatusContinuing || bob.Status != StatusDeparted {
+		t.Errorf("Unexpected statuses %s, %s, %s", carol.Status, alice.Status, bob.Status)
+	}
+	if alice.CommitsChange() != 1 || alice.LinesChange() != -50 {
+		t.Errorf("Expected alice to change by +1 commit and -50 lines, got %d and %d", alice.CommitsChange(), alice.LinesChange())
+	}
+	if percent, ok := percentChange(alice.LinesBefore, alice.LinesAfter); !ok || percent != -50 {
+		t.Errorf("Expected -50%% lines for alice, got %v", percent)
+	}
+	if _, ok := percentChange(carol.LinesBefore, carol.LinesAfter); ok {
+		t.Errorf("Expected no percentage for a new contributor")
+	}
+	if comparison.Total.LinesChange() != 10 {
+		t.Errorf("Expected a total change of 10 lines, got %d", comparison.Total.LinesChange())
+	}
+	if names := comparison.NewContributors(); len(names) != 1 || names[0] != "carol" {
+		t.Errorf("Expected carol as new contributor, got %v", names)
+	}
+	if names := comparison.DepartedContributors(); len(names) != 1 || names[0] != "bob" {
+		t.Errorf("Expected bob as departed contributor, got %v", names)
+	}
+	if err := writeComparison(comparison, FormatNDJSON); err == nil {
+		t.Errorf("Expected ndjson output to be rejected")
+	}
+}
+
+func TestCompareRanges(t *testing.T) {
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo(t)
+	r.commit("alice", when, map[string]string{"a.txt": "1\n"})
+	v1 := r.commit("bob", when.AddDate(0, 0, 1), map[string]string{"b.txt": "1\n2\n"})
+	r.commit("alice", when.AddDate(0, 3, 0), map[string]string{"a.txt": "1\n2\n3\n"})
+	r.commit("carol", when.AddDate(0, 3, 1), map[string]string{"c.txt": "1\n"})
+	if _, err := r.repo.CreateTag("v1", plumbing.NewHash(v1), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revision ranges: the history up to v1 against the commits since
+	before, _ := parseComparisonRange("v1", time.UTC)
+	after, _ := parseComparisonRange("v1..HEAD", time.UTC)
+	comparison, err := CompareRanges(r.repo, newTestStats(), before, after)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if comparison.Total.CommitsBefore != 2 || comparison.Total.CommitsAfter != 2 {
+		t.Errorf("Expected 2 commits on each side, got %d and %d", comparison.Total.CommitsBefore, comparison.Total.CommitsAfter)
+	}
+	if names := comparison.DepartedContributors(); len(names) != 1 || names[0] != "bob" {
+		t.Errorf("Expected bob to have departed after v1, got %v", names)
+	}
+
+	// Tags that look like dates are compared with a prefix
+	if _, err := r.repo.CreateTag("2024", plumbing.NewHash(v1), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "alice", Email: "alice@example.com", When: when},
+		Message: "Release 2024",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ = parseComparisonRange("tag:2024", time.UTC)
+	after, _ = parseComparisonRange("tag:2024..HEAD", time.UTC)
+	if comparison, err = CompareRanges(r.repo, newTestStats(), before, after); err != nil {
+		t.Fatalf("Failed to compare tags: %v", err)
+	}
+	if comparison.Total.CommitsBefore != 2 || comparison.Total.CommitsAfter != 2 {
+		t.Errorf("Expected 2 commits on each side of tag 2024, got %d and %d", comparison.Total.CommitsBefore, comparison.Total.CommitsAfter)
+	}
+
+	// Date windows replace the date range of the settings
+	settings := newTestStats()
+	settings.Since = when.AddDate(1, 0, 0)
+	before, _ = parseComparisonRange("2024-Q1", time.UTC)
+	after, _ = parseComparisonRange("2024-Q2", time.UTC)
+	if comparison, err = CompareRanges(r.repo, settings, before, after); err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if names := comparison.NewContributors(); len(names) != 1 || names[0] != "carol" {
+		t.Errorf("Expected carol to be new in Q2, got %v", names)
+	}
+	if comparison.Total.LinesAfter != 3 {
+		t.Errorf("Expected 3 lines changed in Q2, got %d", comparison.Total.LinesAfter)
+	}
+}
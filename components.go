@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/olekukonko/tablewriter"
+)
+
+// otherComponent is the component of files not matched by any component rule
+const otherComponent = "(other)"
+
+// componentRule maps files matching a glob to a component
+type componentRule struct {
+	Pattern   string
+	Component string
+}
+
+// Grouping assigns files to components, either by their leading directories,
+// by a list of glob rules or by language
+type Grouping struct {
+	Depth     int               // Number of leading path segments naming the component
+	Rules     []componentRule   // Glob rules, the first matching rule wins
+	Languages *LanguageDetector // Group by language when set
+}
+
+// parseGroupBy parses a -group-by value: "dir:N" or "language". Language
+// grouping reads shebang lines from repo.
+func parseGroupBy(value string, repo *git.Repository) (*Grouping, error) {
+	if value == "language" {
+		return &Grouping{Languages: NewLanguageDetector(repo)}, nil
+	}
+
+	depth, ok := strings.CutPrefix(value, "dir:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported grouping %q (expected dir:N or language)", value)
+	}
+	n, err := strconv.Atoi(depth)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid directory depth %q in grouping %q", depth, value)
+	}
+	return &Grouping{Depth: n}, nil
+}
+
+// loadComponents reads a components file. Each line holds a glob followed by
+// the component name; blank lines and lines starting with # are ignored.
+//
+//	services/billing/**   billing
+//	web/**                frontend
+func loadComponents(filename string) (*Grouping, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	grouping := &Grouping{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected a glob followed by a component name", filename, lineNumber)
+		}
+		grouping.Rules = append(grouping.Rules, componentRule{
+			Pattern:   fields[0],
+			Component: strings.Join(fields[1:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return grouping, nil
+}
+
+// Component returns the component a file changed by a commit belongs to
+func (g *Grouping) Component(record *CommitRecord, filename string) string {
+	if g.Languages != nil {
+		return g.Languages.Language(record.Hash, filename)
+	}
+	if len(g.Rules) > 0 {
+		for _, rule := range g.Rules {
+			if matchGlob(rule.Pattern, filename) {
+				return rule.Component
+			}
+		}
+		return otherComponent
+	}
+
+	// Use the first Depth directories; files in the root belong to "."
+	segments := strings.Split(path.Dir(filename), "/")
+	if segments[0] == "." {
+		return "."
+	}
+	if len(segments) > g.Depth {
+		segments = segments[:g.Depth]
+	}
+	return strings.Join(segments, "/")
+}
+
+// matchGlob reports whether a slash-separated path matches a glob. Patterns
+// use path.Match syntax per segment, "**" matches any number of segments and
+// a pattern ending in "/" matches everything below that directory.
+func matchGlob(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against glob segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of segments for "**"
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ComponentCollector splits commit records by component and aggregates each
+// component into its own RepositoryStats
+type ComponentCollector struct {
+	grouping   *Grouping
+	stats      *RepositoryStats
+	Components map[string]*RepositoryStats
+}
+
+// NewComponentCollector creates a collector whose component statistics use
+// the same period, week start and time zone settings as stats
+func NewComponentCollector(grouping *Grouping, stats *RepositoryStats) *ComponentCollector {
+	return &ComponentCollector{
+		grouping:   grouping,
+		stats:      stats,
+		Components: make(map[string]*RepositoryStats),
+	}
+}
+
+// Add aggregates the files of a commit into their components. It can be
+// passed directly to WalkCommits. A commit counts once for every component it touches.
+func (c *ComponentCollector) Add(record *CommitRecord) error {
+	files := make(map[string][]FileChange)
+	for _, change := range record.Files {
+		component := c.grouping.Component(record, change.Name)
+		files[component] = append(files[component], change)
+	}
+
+	for component, changes := range files {
+		componentStats, ok := c.Components[component]
+		if !ok {
+			componentStats = &RepositoryStats{
+				Authors:     make(map[string]*AuthorStats),
+				WeeklyStats: make(map[string]*WeeklyStats),
+				Periods:     make(map[string]*PeriodStats),
+				Period:      c.stats.Period,
+				WeekStart:   c.stats.WeekStart,
+				Location:    c.stats.Location,
+				Bots:        c.stats.Bots,
+				BotAuthors:  c.stats.BotAuthors,
+			}
+			c.Components[component] = componentStats
+		}
+
+		componentRecord := *record
+		componentRecord.Files = changes
+		aggregateCommit(componentStats, &componentRecord)
+	}
+	return nil
+}
+
+// Names returns the component names in sorted order
+func (c *ComponentCollector) Names() []string {
+	names := make([]string, 0, len(c.Components))
+	for name := range c.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// componentRecord is the machine-readable form of a component
+type componentRecord struct {
+	Component    string         `json:"component"`
+	Authors      []authorRecord `json:"authors"`
+	Periods      []periodRecord `json:"periods,omitempty"`
+	TotalCommits int            `json:"total_commits"`
+	TotalLines   int            `json:"total_lines"`
+}
+
+// writeComponents writes the per-component statistics in the requested
+// format, including the time series when showPeriods is set
+func writeComponents(stats *RepositoryStats, collector *ComponentCollector, showPeriods bool, format string) error {
+	switch format {
+	case FormatJSON:
+		records := []componentRecord{}
+		for _, name := range collector.Names() {
+			componentStats := collector.Components[name]
+			record := componentRecord{
+				Component:    name,
+				Authors:      authorRecords(componentStats),
+				TotalCommits: componentStats.TotalCommits,
+				TotalLines:   componentStats.TotalLines,
+			}
+			if showPeriods {
+				record.Periods = periodRecords(componentStats, componentStats.Periods)
+			}
+			records = append(records, record)
+		}
+		return writeJSON(struct {
+			Period     Period            `json:"period,omitempty"`
+			Components []componentRecord `json:"components"`
+		}{periodIf(showPeriods, stats.Period), records})
+	case FormatCSV:
+		if showPeriods {
+			rows := [][]string{{"component", "period", "start", "author", "lines_changed", "commits"}}
+			for _, name := range collector.Names() {
+				componentStats := collector.Components[name]
+				for _, r := range periodRecords(componentStats, componentStats.Periods) {
+					rows = append(rows, []string{name, r.Period, r.Start, r.Author, fmt.Sprintf("%d", r.LinesChanged), fmt.Sprintf("%d", r.Commits)})
+				}
+			}
+			return writeCSV(rows)
+		}
+		rows := [][]string{{"component", "author", "commits", "lines_changed"}}
+		for _, name := range collector.Names() {
+			for _, r := range authorRecords(collector.Components[name]) {
+				rows = append(rows, []string{name, r.Author, fmt.Sprintf("%d", r.Commits), fmt.Sprintf("%d", r.LinesChanged)})
+			}
+		}
+		return writeCSV(rows)
+	default:
+		if showPeriods {
+			for _, name := range collector.Names() {
+				componentStats := collector.Components[name]
+				fmt.Printf("Component: %s\n", name)
+				displayPeriodStats(componentStats, componentStats.Periods, stats.Period)
+			}
+			return nil
+		}
+		displayComponents(stats, collector)
+		return nil
+	}
+}
+
+// periodIf returns period when show is set, or an empty period otherwise
+func periodIf(show bool, period Period) Period {
+	if !show {
+		return ""
+	}
+	return period
+}
+
+// displayComponents displays author×component matrices of lines changed and
+// commits in ASCII tables
+func displayComponents(stats *RepositoryStats, collector *ComponentCollector) {
+	names := collector.Names()
+	authors := sortedAuthors(stats)
+
+	for _, matrix := range []struct {
+		title string
+		value func(*AuthorStats) int
+		total func(*RepositoryStats) int
+	}{
+		{"Lines Changed", func(a *AuthorStats) int { return a.LinesChanged }, func(s *RepositoryStats) int { return s.TotalLines }},
+		{"Commits", func(a *AuthorStats) int { return a.CommitCount }, func(s *RepositoryStats) int { return s.TotalCommits }},
+	} {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(append(append([]string{matrix.title}, names...), "TOTAL"))
+		table.SetBorder(true)
+		table.SetAutoFormatHeaders(false)
+
+		// One row per author, with the author's repository total last
+		for _, author := range authors {
+			row := []string{author.Name}
+			for _, name := range names {
+				value := 0
+				if componentAuthor, ok := collector.Components[name].Authors[author.Name]; ok {
+					value = matrix.value(componentAuthor)
+				}
+				row = append(row, fmt.Sprintf("%d", value))
+			}
+			table.Append(append(row, fmt.Sprintf("%d", matrix.value(author))))
+		}
+
+		row := []string{"TOTAL"}
+		for _, name := range names {
+			row = append(row, fmt.Sprintf("%d", matrix.total(collector.Components[name])))
+		}
+		table.Append(append(row, fmt.Sprintf("%d", matrix.total(stats))))
+		table.Render()
+	}
+}
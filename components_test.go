@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	grouping, err := parseGroupBy("dir:2", nil)
+	if err != nil || grouping.Depth != 2 {
+		t.Errorf("Expected a depth of 2, got %+v (%v)", grouping, err)
+	}
+	for _, value := range []string{"dir:0", "dir:x", "team"} {
+		if _, err := parseGroupBy(value, nil); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestGroupingByDirectory(t *testing.T) {
+	grouping := &Grouping{Depth: 2}
+	tests := map[string]string{
+		"main.go":                      ".",
+		"services/api.go":              "services",
+		"services/billing/invoice.go":  "services/billing",
+		"services/billing/pdf/page.go": "services/billing",
+	}
+	for filename, expected := range tests {
+		if component := grouping.Component(&CommitRecord{}, filename); component != expected {
+			t.Errorf("Expected %s to belong to %q, got %q", filename, expected, component)
+		}
+	}
+}
+
+func TestLoadComponents(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "components")
+	content := "# Monorepo components\nservices/billing/  billing\n**/*.ts  web frontend\n\ndocs/*.md docs\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	grouping, err := loadComponents(filename)
+	if err != nil {
+		t.Fatalf("Failed to load components: %v", err)
+	}
+	tests := map[string]string{
+		"services/billing/invoice.go": "billing",
+		"services/billing/ui/app.ts":  "billing",
+		"web/src/app.ts":              "web frontend",
+		"app.ts":                      "web frontend",
+		"docs/intro.md":               "docs",
+		"docs/api/intro.md":           otherComponent,
+		"main.go":                     otherComponent,
+	}
+	for filename, expected := range tests {
+		if component := grouping.Component(&CommitRecord{}, filename); component != expected {
+			t.Errorf("Expected %s to belong to %q, got %q", filename, expected, component)
+		}
+	}
+
+	if err := os.WriteFile(filename, []byte("billing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadComponents(filename); err == nil {
+		t.Errorf("Expected an error for a rule without a component")
+	}
+}
+
+func TestComponentCollector(t *testing.T) {
+	stats := newTestStats()
+	collector := NewComponentCollector(&Grouping{Depth: 1}, stats)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	records := []*CommitRecord{
+		{Author: "Alice", When: when, Files: []FileChange{{Name: "api/a.go", Additions: 10}, {Name: "web/b.ts", Additions: 5}}},
+		{Author: "Bob", When: when.AddDate(0, 0, 7), Files: []FileChange{{Name: "api/a.go", Additions: 2, Deletions: 1}}},
+	}
+	for _, record := range records {
+		aggregateCommit(stats, record)
+		collector.Add(record)
+	}
+
+	if names := collector.Names(); len(names) != 2 || names[0] != "api" || names[1] != "web" {
+		t.Fatalf("Unexpected components: %v", names)
+	}
+
+	api := collector.Components["api"]
+	if api.TotalCommits != 2 || api.TotalLines != 13 {
+		t.Errorf("Expected 2 commits and 13 lines in api, got %d and %d", api.TotalCommits, api.TotalLines)
+	}
+	if api.Authors["Alice"].LinesChanged != 10 || api.Authors["Bob"].LinesChanged != 3 {
+		t.Errorf("Unexpected api authors: %+v %+v", api.Authors["Alice"], api.Authors["Bob"])
+	}
+	if len(api.Periods) != 2 {
+		t.Errorf("Expected 2 weeks in api, got %d", len(api.Periods))
+	}
+
+	web := collector.Components["web"]
+	if web.TotalCommits != 1 || web.TotalLines != 5 || web.Authors["Bob"] != nil {
+		t.Errorf("Unexpected web stats: %d commits, %d lines, %v", web.TotalCommits, web.TotalLines, web.Authors)
+	}
+
+	// The repository totals are unaffected by the split
+	if stats.TotalLines != 18 {
+		t.Errorf("Expected 18 lines in total, got %d", stats.TotalLines)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the name of the configuration file read from the repository
+// root and from the user's home directory
+const ConfigFile = ".gitstics.yaml"
+
+// Config holds the settings declared in .gitstics.yaml files. Every scalar
+// setting has the name and meaning of the command-line flag it defaults.
+//
+//	ext: .go
+//	ignore: [vendor/, "*.pb.go"]
+//	aliases:
+//	  alice@old-laptop.local: Alice Smith
+//	teams:
+//	  alice@example.com: Platform
+//	merges: exclude
+//	period: month
+//	format: json
+type Config struct {
+	Ext       string            `yaml:"ext,omitempty"`
+	Since     string            `yaml:"since,omitempty"`
+	Until     string            `yaml:"until,omitempty"`
+	Bots      string            `yaml:"bots,omitempty"`
+	Merges    string            `yaml:"merges,omitempty"`
+	Period    string            `yaml:"period,omitempty"`
+	WeekStart string            `yaml:"week_start,omitempty"`
+	Timezone  string            `yaml:"timezone,omitempty"`
+	Format    string            `yaml:"format,omitempty"`
+	Ignore    []string          `yaml:"-"`                 // Ignore globs; a file may give a single one
+	Aliases   map[string]string `yaml:"aliases,omitempty"` // Lower-case name or email to canonical author name
+	Teams     map[string]string `yaml:"teams,omitempty"`   // Lower-case name or email to team
+	Sources   []string          `yaml:"-"`                 // Files the configuration was loaded from, in order
+}
+
+// configSetting ties a scalar configuration key to its command-line flag
+type configSetting struct {
+	key   string
+	flag  string
+	value *string
+}
+
+// settings lists the scalar settings of the configuration in output order
+func (c *Config) settings() []configSetting {
+	return []configSetting{
+		{"ext", "ext", &c.Ext},
+		{"since", "since", &c.Since},
+		{"until", "until", &c.Until},
+		{"bots", "bots", &c.Bots},
+		{"merges", "merges", &c.Merges},
+		{"period", "period", &c.Period},
+		{"week_start", "week-start", &c.WeekStart},
+		{"timezone", "tz", &c.Timezone},
+		{"format", "format", &c.Format},
+	}
+}
+
+// newConfig creates an empty configuration
+func newConfig() *Config {
+	return &Config{
+		Aliases: make(map[string]string),
+		Teams:   make(map[string]string),
+	}
+}
+
+// loadConfig reads a configuration file
+func loadConfig(filename string) (*Config, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := newConfig()
+	config.Sources = []string{filename}
+	document := struct {
+		*Config `yaml:",inline"`
+		Ignore  yaml.Node `yaml:"ignore"`
+	}{Config: config}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	// The ignore setting takes a list of globs or a single one
+	switch document.Ignore.Kind {
+	case yaml.ScalarNode:
+		if document.Ignore.Tag != "!!null" && document.Ignore.Value != "" {
+			config.Ignore = []string{document.Ignore.Value}
+		}
+	case yaml.SequenceNode:
+		if err := document.Ignore.Decode(&config.Ignore); err != nil {
+			return nil, fmt.Errorf("%s: ignore: %w", filename, err)
+		}
+	case 0:
+	default:
+		return nil, fmt.Errorf("%s: ignore: expected a list of globs", filename)
+	}
+
+	if config.Aliases, err = normalizeIdentities(config.Aliases); err != nil {
+		return nil, fmt.Errorf("%s: aliases: %w", filename, err)
+	}
+	if config.Teams, err = normalizeIdentities(config.Teams); err != nil {
+		return nil, fmt.Errorf("%s: teams: %w", filename, err)
+	}
+	return config, nil
+}
+
+// normalizeIdentities lower-cases the author names or emails of a mapping of
+// identities, which are matched ignoring case
+func normalizeIdentities(identities map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(identities))
+	for identity, name := range identities {
+		if name == "" {
+			return nil, fmt.Errorf("%s: expected a name", identity)
+		}
+		normalized[strings.ToLower(strings.TrimSpace(identity))] = name
+	}
+	return normalized, nil
+}
+
+// userConfigFile returns the path of the user-level configuration file
+func userConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ConfigFile)
+}
+
+// loadConfigs loads the user-level configuration followed by the one at the
+// repository root, skipping files that do not exist
+func loadConfigs(repoPath string) (*Config, error) {
+	config := newConfig()
+	for _, filename := range []string{userConfigFile(), filepath.Join(repoPath, ConfigFile)} {
+		if filename == "" {
+			continue
+		}
+		file, err := loadConfig(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		config.Merge(file)
+	}
+	return config, nil
+}
+
+// Merge layers another configuration over this one: its scalar settings
+// replace these, its ignore globs are added and its aliases and teams
+// replace entries for the same identities
+func (c *Config) Merge(other *Config) {
+	settings := other.settings()
+	for i, setting := range c.settings() {
+		if *settings[i].value != "" {
+			*setting.value = *settings[i].value
+		}
+	}
+	c.Ignore = append(c.Ignore, other.Ignore...)
+	for identity, name := range other.Aliases {
+		c.Aliases[identity] = name
+	}
+	for identity, team := range other.Teams {
+		c.Teams[identity] = team
+	}
+	c.Sources = append(c.Sources, other.Sources...)
+}
+
+// Apply resolves the configuration against the command line: flags that were
+// not given take their value from the configuration. Afterwards every setting
+// holds the value in effect, including flag defaults.
+func (c *Config) Apply(flags *flag.FlagSet) error {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, setting := range c.settings() {
+		// Settings for flags the command does not have are left as they are
+		f := flags.Lookup(setting.flag)
+		if f == nil {
+			continue
+		}
+		if !given[setting.flag] && *setting.value != "" {
+			if err := flags.Set(setting.flag, *setting.value); err != nil {
+				return fmt.Errorf("%s: %w", setting.key, err)
+			}
+		}
+		*setting.value = f.Value.String()
+	}
+	return nil
+}
+
+// TeamMap returns the configured teams, or nil when there are none
+func (c *Config) TeamMap() *TeamMap {
+	if len(c.Teams) == 0 {
+		return nil
+	}
+	return &TeamMap{members: c.Teams}
+}
+
+// resolveAlias returns the canonical name of an author, matching the email
+// before the name, or the name itself when it has no alias
+func resolveAlias(aliases map[string]string, name, email string) string {
+	if canonical, ok := aliases[strings.ToLower(email)]; ok {
+		return canonical
+	}
+	if canonical, ok := aliases[strings.ToLower(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// writeConfig prints a configuration as YAML, in the format it is read in
+func writeConfig(w io.Writer, config *Config) error {
+	if len(config.Sources) == 0 {
+		fmt.Fprintln(w, "# No configuration files found; showing defaults and flags")
+	}
+	for _, source := range config.Sources {
+		fmt.Fprintf(w, "# Loaded from %s\n", source)
+	}
+
+	document := struct {
+		*Config `yaml:",inline"`
+		Ignore  []string `yaml:"ignore,omitempty"`
+	}{config, config.Ignore}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
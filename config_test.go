@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// writeConfigFile writes a .gitstics.yaml file into dir
+func writeConfigFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	filename := filepath.Join(dir, ConfigFile)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadConfigs(t *testing.T) {
+	home, repo := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	writeConfigFile(t, home, "format: csv\nperiod: week\nignore: [\"*.min.js\"]\naliases:\n  alice@laptop: Alice\n")
+	writeConfigFile(t, repo, "period: month\nignore:\n  - vendor/\naliases:\n  bob@laptop: Bob\nteams:\n  alice: Platform\n")
+
+	config, err := loadConfigs(repo)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if config.Format != "csv" || config.Period != "month" {
+		t.Errorf("Expected format csv and period month, got %q and %q", config.Format, config.Period)
+	}
+	if !reflect.DeepEqual(config.Ignore, []string{"*.min.js", "vendor/"}) {
+		t.Errorf("Expected the ignore globs of both files, got %v", config.Ignore)
+	}
+	if len(config.Aliases) != 2 || config.Teams["alice"] != "Platform" || len(config.Sources) != 2 {
+		t.Errorf("Expected aliases and teams merged from both files, got %+v", config)
+	}
+
+	// Any YAML is accepted: escapes, flow mappings, block scalars and anchors
+	writeConfigFile(t, repo, "format: \"js\\u006Fn\"\nsince: 2024-01-01\nignore: '*.pb.go'\n"+
+		"aliases: {\"Al\\tSmith\": &alice Alice, al@laptop: *alice}\nteams:\n  alice: >-\n    Platform\n    Team\n")
+	config, err = loadConfigs(repo)
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if config.Format != "json" || config.Since != "2024-01-01" || !reflect.DeepEqual(config.Ignore, []string{"*.min.js", "*.pb.go"}) {
+		t.Errorf("Unexpected settings %+v", config)
+	}
+	if config.Aliases["al\tsmith"] != "Alice" || config.Aliases["al@laptop"] != "Alice" || config.Teams["alice"] != "Platform Team" {
+		t.Errorf("Unexpected aliases %v and teams %v", config.Aliases, config.Teams)
+	}
+
+	writeConfigFile(t, repo, "colour: blue\n")
+	if _, err := loadConfigs(repo); err == nil {
+		t.Errorf("Expected an error for an unknown setting")
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	flags := flag.NewFlagSet("gitstics", flag.ContinueOnError)
+	flags.String("ext", "", "")
+	flags.String("since", "", "")
+	flags.String("until", "", "")
+	flags.String("bots", "include", "")
+	flags.String("merges", "include", "")
+	flags.String("period", "", "")
+	flags.String("week-start", "monday", "")
+	flags.String("tz", "", "")
+	format := flags.String("format", "table", "")
+	flags.String("ignore", "", "")
+	if err := flags.Parse([]string{"-format", "json", "-ignore", "dist/, *.map"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := newConfig()
+	config.Ext = ".go"
+	config.Format = "csv"
+	config.Ignore = []string{"vendor/"}
+	if err := config.Apply(flags); err != nil {
+		t.Fatalf("Failed to apply configuration: %v", err)
+	}
+
+	if *format != "json" || config.Format != "json" {
+		t.Errorf("Expected the -format flag to override the configuration, got %q", *format)
+	}
+	if flags.Lookup("ext").Value.String() != ".go" {
+		t.Errorf("Expected the configured extension to set -ext")
+	}
+	if config.Bots != "include" || config.WeekStart != "monday" {
+		t.Errorf("Expected flag defaults in the effective configuration, got %+v", config)
+	}
+	if !reflect.DeepEqual(config.Ignore, []string{"vendor/"}) {
+		t.Errorf("Expected -ignore files to stay out of the configured globs, got %v", config.Ignore)
+	}
+
+	// The printed configuration reads back the same, whatever needs quoting
+	config.Ignore = append(config.Ignore, "*.map")
+	config.Aliases["o'brien@example.com"] = "Pat O'Brien"
+	config.Teams["bob: the builder"] = "#ops"
+	var out bytes.Buffer
+	if err := writeConfig(&out, config); err != nil {
+		t.Fatalf("Failed to write configuration: %v", err)
+	}
+	read, err := loadConfig(writeConfigFile(t, t.TempDir(), out.String()))
+	if err != nil {
+		t.Fatalf("Failed to read back the written configuration: %v\n%s", err, out.String())
+	}
+	read.Sources = config.Sources
+	if !reflect.DeepEqual(read, config) {
+		t.Errorf("Expected %+v to read back, got %+v from:\n%s", config, read, out.String())
+	}
+}
+
+func TestMatchIgnoreGlob(t *testing.T) {
+	tests := []struct {
+		pattern, filename string
+		expected          bool
+	}{
+		{"*.pb.go", "api/v1/service.pb.go", true},
+		{"*.pb.go", "service.go", false},
+		{"vendor/", "vendor/lib/a.go", true},
+		{"vendor/", "src/vendor/a.go", true},
+		{"vendor/", "vendor", false},
+		{"/build", "build/out.js", true},
+		{"/build", "src/build/out.js", false},
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/api/intro.md", false},
+		{"**/testdata/", "pkg/testdata/fixture.json", true},
+		{"go.sum", "go.sum", true},
+	}
+	for _, test := range tests {
+		if matched := matchIgnoreGlob(test.pattern, test.filename); matched != test.expected {
+			t.Errorf("matchIgnoreGlob(%q, %q) = %v, expected %v", test.pattern, test.filename, matched, test.expected)
+		}
+	}
+
+	// Files given to -ignore are exact paths, globs come from the configuration
+	stats := newTestStats()
+	stats.IgnoreFiles["README.md"] = true
+	stats.IgnoreGlobs = []string{"*.pb.go"}
+	if stats.IncludesFile("README.md") || !stats.IncludesFile("docs/README.md") || stats.IncludesFile("api/a.pb.go") {
+		t.Errorf("Expected README.md to be ignored at the root only, along with *.pb.go files")
+	}
+}
+
+func TestAliasesAndMergePolicy(t *testing.T) {
+	r := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	first := r.commit("alice", when, map[string]string{"main.go": "package main\n"})
+	second := r.commit("al", when.Add(time.Hour), map[string]string{"util.go": "package main\n", "vendor/lib.go": "package lib\n"})
+
+	// Merge the two commits back together
+	if err := os.WriteFile(filepath.Join(r.dir, "merge.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.wt.Add("merge.go"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "bob", Email: "bob@example.com", When: when.Add(2 * time.Hour)}
+	if _, err := r.wt.Commit("Merge", &git.CommitOptions{
+		Author:    signature,
+		Committer: signature,
+		Parents:   []plumbing.Hash{plumbing.NewHash(second), plumbing.NewHash(first)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := newTestStats()
+	stats.Aliases = map[string]string{"al@example.com": "alice"}
+	stats.IgnoreGlobs = []string{"vendor/"}
+	stats.SkipMerges = true
+	if err := analyzeRepository(r.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+
+	alice := stats.Authors["alice"]
+	if len(stats.Authors) != 1 || alice == nil || alice.CommitCount != 2 {
+		t.Fatalf("Expected both commits credited to alice and the merge skipped, got %v", stats.Authors)
+	}
+	if alice.LinesChanged != 2 {
+		t.Errorf("Expected 2 lines changed outside vendor/, got %d", alice.LinesChanged)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// CouplingOptions controls which commits and files take part in the coupling analysis
+type CouplingOptions struct {
+	MinRevisions      int // Files changed in fewer commits than this are left out
+	MaxFilesPerCommit int // Commits touching more files than this are skipped (0 = no limit)
+}
+
+// FileCoupling describes how often two files change in the same commit
+type FileCoupling struct {
+	FileA         string
+	FileB         string
+	SharedCommits int     // Commits changing both files
+	RevisionsA    int     // Commits changing FileA
+	RevisionsB    int     // Commits changing FileB
+	Support       float64 // SharedCommits as a percentage of all analyzed commits
+	ConfidenceAB  float64 // Percentage of FileA's commits that also change FileB
+	ConfidenceBA  float64 // Percentage of FileB's commits that also change FileA
+}
+
+// CouplingCollector accumulates change coupling from a stream of commit records
+type CouplingCollector struct {
+	options   CouplingOptions
+	commits   int
+	skipped   int
+	revisions map[string]int
+	pairs     map[[2]string]int
+}
+
+// NewCouplingCollector creates a collector using the given options
+func NewCouplingCollector(options CouplingOptions) *CouplingCollector {
+	return &CouplingCollector{
+		options:   options,
+		revisions: make(map[string]int),
+		pairs:     make(map[[2]string]int),
+	}
+}
+
+// Add records the files changed together in a commit. It can be passed
+// directly to WalkCommits.
+func (c *CouplingCollector) Add(record *CommitRecord) error {
+	// Skip huge commits that touch everything, such as reformats and renames
+	if c.options.MaxFilesPerCommit > 0 && len(record.Files) > c.options.MaxFilesPerCommit {
+		c.skipped++
+		return nil
+	}
+	c.commits++
+
+	files := make([]string, 0, len(record.Files))
+	for _, file := range record.Files {
+		files = append(files, file.Name)
+		c.revisions[file.Name]++
+	}
+	sort.Strings(files)
+
+	// Count every pair of files changed together
+	for i := 0; i < len(files); i++ {
+		for j := i + 1; j < len(files); j++ {
+			c.pairs[[2]string{files[i], files[j]}]++
+		}
+	}
+	return nil
+}
+
+// Skipped returns the number of commits skipped for touching too many files
+func (c *CouplingCollector) Skipped() int {
+	return c.skipped
+}
+
+// Results returns the coupled file pairs, strongest coupling first
+func (c *CouplingCollector) Results() []*FileCoupling {
+	results := []*FileCoupling{}
+	for pair, shared := range c.pairs {
+		revisionsA, revisionsB := c.revisions[pair[0]], c.revisions[pair[1]]
+		if revisionsA < c.options.MinRevisions || revisionsB < c.options.MinRevisions {
+			continue
+		}
+
+		results = append(results, &FileCoupling{
+			FileA:         pair[0],
+			FileB:         pair[1],
+			SharedCommits: shared,
+			RevisionsA:    revisionsA,
+			RevisionsB:    revisionsB,
+			Support:       float64(shared) / float64(c.commits) * 100,
+			ConfidenceAB:  float64(shared) / float64(revisionsA) * 100,
+			ConfidenceBA:  float64(shared) / float64(revisionsB) * 100,
+		})
+	}
+
+	// Sort by the stronger direction of the coupling, then by shared commits
+	sort.Slice(results, func(i, j int) bool {
+		ci := maxFloat(results[i].ConfidenceAB, results[i].ConfidenceBA)
+		cj := maxFloat(results[j].ConfidenceAB, results[j].ConfidenceBA)
+		if ci != cj {
+			return ci > cj
+		}
+		if results[i].SharedCommits != results[j].SharedCommits {
+			return results[i].SharedCommits > results[j].SharedCommits
+		}
+		if results[i].FileA != results[j].FileA {
+			return results[i].FileA < results[j].FileA
+		}
+		return results[i].FileB < results[j].FileB
+	})
+	return results
+}
+
+// maxFloat returns the larger of two floats
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// writeCoupling writes the coupling report in the requested format
+func writeCoupling(collector *CouplingCollector, format string) error {
+	results := collector.Results()
+
+	switch format {
+	case FormatJSON:
+		type couplingRecord struct {
+			FileA         string  `json:"file_a"`
+			FileB         string  `json:"file_b"`
+			SharedCommits int     `json:"shared_commits"`
+			RevisionsA    int     `json:"revisions_a"`
+			RevisionsB    int     `json:"revisions_b"`
+			Support       float64 `json:"support_percent"`
+			ConfidenceAB  float64 `json:"confidence_ab_percent"`
+			ConfidenceBA  float64 `json:"confidence_ba_percent"`
+		}
+		records := make([]couplingRecord, 0, len(results))
+		for _, r := range results {
+			records = append(records, couplingRecord(*r))
+		}
+		return writeJSON(struct {
+			Pairs          []couplingRecord `json:"pairs"`
+			SkippedCommits int              `json:"skipped_commits"`
+		}{records, collector.Skipped()})
+	case FormatCSV:
+		rows := [][]string{{"file_a", "file_b", "shared_commits", "revisions_a", "revisions_b", "support_percent", "confidence_ab_percent", "confidence_ba_percent"}}
+		for _, r := range results {
+			rows = append(rows, []string{
+				r.FileA,
+				r.FileB,
+				fmt.Sprintf("%d", r.SharedCommits),
+				fmt.Sprintf("%d", r.RevisionsA),
+				fmt.Sprintf("%d", r.RevisionsB),
+				fmt.Sprintf("%.1f", r.Support),
+				fmt.Sprintf("%.1f", r.ConfidenceAB),
+				fmt.Sprintf("%.1f", r.ConfidenceBA),
+			})
+		}
+		return writeCSV(rows)
+	default:
+		displayCoupling(results, collector.Skipped())
+		return nil
+	}
+}
+
+// displayCoupling displays coupled file pairs in an ASCII table
+func displayCoupling(results []*FileCoupling, skipped int) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"File A", "File B", "Shared Commits", "Support %", "Confidence A→B %", "Confidence B→A %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+
+	for _, r := range results {
+		table.Append([]string{
+			r.FileA,
+			r.FileB,
+			fmt.Sprintf("%d", r.SharedCommits),
+			fmt.Sprintf("%.1f%%", r.Support),
+			fmt.Sprintf("%.1f%%", r.ConfidenceAB),
+			fmt.Sprintf("%.1f%%", r.ConfidenceBA),
+		})
+	}
+	table.Render()
+
+	if skipped > 0 {
+		fmt.Printf("Skipped %d commits touching too many files\n", skipped)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestCouplingCollector(t *testing.T) {
+	collector := NewCouplingCollector(CouplingOptions{MinRevisions: 2, MaxFilesPerCommit: 3})
+
+	commits := [][]string{
+		{"api.go", "api_test.go"},
+		{"api.go", "api_test.go", "README.md"},
+		{"api.go"},
+		{"api.go", "api_test.go"},
+		{"README.md"},
+		{"a.go", "b.go", "c.go", "d.go"}, // Too large, skipped
+	}
+	for _, files := range commits {
+		record := &CommitRecord{}
+		for _, name := range files {
+			record.Files = append(record.Files, FileChange{Name: name})
+		}
+		collector.Add(record)
+	}
+
+	if collector.Skipped() != 1 {
+		t.Errorf("Expected 1 skipped commit, got %d", collector.Skipped())
+	}
+
+	results := collector.Results()
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 coupled pairs, got %d", len(results))
+	}
+
+	// api_test.go always changes with api.go
+	top := results[0]
+	if top.FileA != "api.go" || top.FileB != "api_test.go" {
+		t.Fatalf("Expected api.go/api_test.go to be the strongest pair, got %s/%s", top.FileA, top.FileB)
+	}
+	if top.SharedCommits != 3 || top.RevisionsA != 4 || top.RevisionsB != 3 {
+		t.Errorf("Unexpected counts: %+v", top)
+	}
+	if top.ConfidenceAB != 75 || top.ConfidenceBA != 100 || top.Support != 60 {
+		t.Errorf("Unexpected percentages: %+v", top)
+	}
+}
+
+func TestCouplingCollectorMinRevisions(t *testing.T) {
+	collector := NewCouplingCollector(CouplingOptions{MinRevisions: 2})
+	collector.Add(&CommitRecord{Files: []FileChange{{Name: "a.go"}, {Name: "b.go"}}})
+
+	if results := collector.Results(); len(results) != 0 {
+		t.Errorf("Expected files below the revision threshold to be left out, got %d pairs", len(results))
+	}
+}
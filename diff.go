@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// lineOp is a run of lines kept, added or deleted by a file change
+type lineOp struct {
+	Type  fdiff.Operation
+	Lines int
+}
+
+// countLines counts the lines in a chunk of a patch, including a final line
+// without a trailing newline
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	lines := strings.Count(s, "\n")
+	if s[len(s)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// diffOptions controls how much detail fileChangeFromPatch extracts from a patch
+type diffOptions struct {
+	KeepOps       bool // Retain the sequence of kept, added and deleted lines
+	ClassifyLines bool // Count changed lines as code, comment or blank
+}
+
+// diffOptionsFor returns the diff options needed by the settings in stats
+func diffOptionsFor(stats *RepositoryStats) diffOptions {
+	return diffOptions{
+		KeepOps:       stats.ReworkWindow > 0,
+		ClassifyLines: stats.ClassifyLines,
+	}
+}
+
+// fileChangeFromPatch converts a file patch into a FileChange. It returns false
+// for patches without content, such as binary files and submodule updates.
+func fileChangeFromPatch(fp fdiff.FilePatch, options diffOptions) (FileChange, bool) {
+	chunks := fp.Chunks()
+	if len(chunks) == 0 {
+		return FileChange{}, false
+	}
+
+	// Name the change after the new path, or the old one for deleted files
+	change := FileChange{}
+	from, to := fp.Files()
+	if to != nil {
+		change.Name = to.Path()
+	} else if from != nil {
+		change.Name = from.Path()
+	}
+
+	for _, chunk := range chunks {
+		lines := countLines(chunk.Content())
+		if lines == 0 {
+			continue
+		}
+
+		switch chunk.Type() {
+		case fdiff.Add:
+			change.Additions += lines
+		case fdiff.Delete:
+			change.Deletions += lines
+		}
+		if options.KeepOps {
+			change.ops = append(change.ops, lineOp{Type: chunk.Type(), Lines: lines})
+		}
+	}
+	if options.ClassifyLines {
+		classifyChunks(&change, chunks)
+	}
+	return change, true
+}
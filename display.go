@@ -3,122 +3,202 @@ package main
 import (
 	"fmt"
 	"os"
-	"sort"
 
 	"github.com/olekukonko/tablewriter"
 )
 
 // displayStats displays repository statistics in an ASCII table
 func displayStats(stats *RepositoryStats) {
-	// Create a slice of authors for sorting
-	authors := make([]*AuthorStats, 0, len(stats.Authors))
-	for _, author := range stats.Authors {
-		authors = append(authors, author)
-	}
-
 	// Sort authors by commit count (descending)
-	sort.Slice(authors, func(i, j int) bool {
-		return authors[i].CommitCount > authors[j].CommitCount
-	})
+	authors := sortedAuthors(stats)
 
 	// Create and configure the table
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Author", "Commits", "Lines Changed", "Lines Changed %", "Commits %"})
+	showRework := stats.ReworkWindow > 0
+	header := []string{"Author", "Commits", "Lines Changed", "Lines Changed %", "Commits %"}
+	if showRework {
+		header = []string{"Author", "Commits", "Lines Changed", "Rework %", "Lines Changed %", "Commits %"}
+	}
+	if stats.ClassifyLines {
+		header = insertColumn(header, 3, "Code Lines")
+	}
+	if stats.ByTeam {
+		header[0] = "Team"
+	}
+	header = append(header, stats.Repositories...)
+	table.SetHeader(header)
 	table.SetBorder(true)
 	table.SetAutoFormatHeaders(false)
 
-	// Add author rows
+	// Add author rows, with bots in a group of their own when requested
+	bots := &AuthorStats{Name: "BOTS"}
 	for _, author := range authors {
-		linesPercent := 0.0
-		if stats.TotalLines > 0 {
-			linesPercent = float64(author.LinesChanged) / float64(stats.TotalLines) * 100
+		if stats.Bots == BotsSeparate && author.Bot {
+			addAuthorTotals(bots, author)
+			continue
 		}
-
-		commitsPercent := 0.0
-		if stats.TotalCommits > 0 {
-			commitsPercent = float64(author.CommitCount) / float64(stats.TotalCommits) * 100
+		table.Append(authorRow(stats, author))
+	}
+	if bots.CommitCount > 0 {
+		table.Append(make([]string, len(header)))
+		for _, author := range authors {
+			if author.Bot {
+				table.Append(authorRow(stats, author))
+			}
 		}
-
-		table.Append([]string{
-			author.Name,
-			fmt.Sprintf("%d", author.CommitCount),
-			fmt.Sprintf("%d", author.LinesChanged),
-			fmt.Sprintf("%.1f%%", linesPercent),
-			fmt.Sprintf("%.1f%%", commitsPercent),
-		})
+		table.Append(authorRow(stats, bots))
 	}
 
 	// Add total row
-	table.Append([]string{
+	row := []string{
 		"TOTAL",
 		fmt.Sprintf("%d", stats.TotalCommits),
 		fmt.Sprintf("%d", stats.TotalLines),
 		"100%",
 		"100%",
-	})
+	}
+	if showRework {
+		reworked, added := 0, 0
+		for _, author := range authors {
+			reworked += author.ReworkedLines
+			added += author.LinesAdded
+		}
+		row = insertColumn(row, 3, fmt.Sprintf("%.1f%%", reworkPercent(reworked, added)))
+	}
+	if stats.ClassifyLines {
+		row = insertColumn(row, 3, fmt.Sprintf("%d", stats.TotalCodeLines))
+	}
+	table.Append(append(row, repositoryColumns(stats, repositoryTotals(stats))...))
 
 	// Render the table
 	table.Render()
 }
 
+// authorRow formats an author's statistics as a row of the author table
+func authorRow(stats *RepositoryStats, author *AuthorStats) []string {
+	linesPercent := 0.0
+	if stats.TotalLines > 0 {
+		linesPercent = float64(author.LinesChanged) / float64(stats.TotalLines) * 100
+	}
+
+	commitsPercent := 0.0
+	if stats.TotalCommits > 0 {
+		commitsPercent = float64(author.CommitCount) / float64(stats.TotalCommits) * 100
+	}
+
+	row := []string{
+		author.Name,
+		fmt.Sprintf("%d", author.CommitCount),
+		fmt.Sprintf("%d", author.LinesChanged),
+		fmt.Sprintf("%.1f%%", linesPercent),
+		fmt.Sprintf("%.1f%%", commitsPercent),
+	}
+	if stats.ReworkWindow > 0 {
+		row = insertColumn(row, 3, fmt.Sprintf("%.1f%%", reworkPercent(author.ReworkedLines, author.LinesAdded)))
+	}
+	if stats.ClassifyLines {
+		row = insertColumn(row, 3, fmt.Sprintf("%d", author.CodeLinesChanged))
+	}
+	return append(row, repositoryColumns(stats, author.Repositories)...)
+}
+
+// addAuthorTotals adds the counts of an author to a group total
+func addAuthorTotals(total *AuthorStats, author *AuthorStats) {
+	total.CommitCount += author.CommitCount
+	total.LinesChanged += author.LinesChanged
+	total.LinesAdded += author.LinesAdded
+	total.ReworkedLines += author.ReworkedLines
+	total.CodeLinesChanged += author.CodeLinesChanged
+	for label, lines := range author.Repositories {
+		if total.Repositories == nil {
+			total.Repositories = make(map[string]int)
+		}
+		total.Repositories[label] += lines
+	}
+}
+
 // displayWeeklyStats displays weekly code frequency statistics in an ASCII table
 func displayWeeklyStats(stats *RepositoryStats) {
-	// Create a slice of weeks for sorting
-	weeks := make([]*WeeklyStats, 0, len(stats.WeeklyStats))
-	for _, week := range stats.WeeklyStats {
-		weeks = append(weeks, week)
-	}
+	displayPeriodStats(stats, stats.WeeklyStats, PeriodWeek)
+}
 
-	// Sort weeks by date (ascending)
-	sort.Slice(weeks, func(i, j int) bool {
-		return weeks[i].Week.Before(weeks[j].Week)
-	})
+// displayPeriodStats displays a time series of per-author statistics in an ASCII table
+func displayPeriodStats(stats *RepositoryStats, series map[string]*PeriodStats, period Period) {
+	// Sort periods by date (ascending)
+	periods := sortedPeriods(series)
 
 	// Create and configure the table
+	label := period.Label()
+	showRework := stats.ReworkWindow > 0
+	header := []string{label, "Author", "Lines Changed", "Lines/" + label, "Commits"}
+	if showRework {
+		header = insertColumn(header, 3, "Rework %")
+	}
+	if stats.ClassifyLines {
+		header = insertColumn(header, 3, "Code Lines")
+	}
+	header = append(header, stats.Repositories...)
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Week", "Author", "Lines Changed", "Lines/Week", "Commits"})
+	table.SetHeader(header)
 	table.SetBorder(true)
 	table.SetAutoFormatHeaders(false)
 
-	// Add rows for each week and author
-	for _, week := range weeks {
-		// Create a slice of authors for this week
-		authors := make([]*WeeklyAuthorStats, 0, len(week.Authors))
-		for _, author := range week.Authors {
-			authors = append(authors, author)
-		}
-
+	// Add rows for each period and author
+	for _, periodStats := range periods {
 		// Sort authors by lines changed (descending)
-		sort.Slice(authors, func(i, j int) bool {
-			return authors[i].LinesChanged > authors[j].LinesChanged
-		})
+		authors := sortedPeriodAuthors(periodStats)
 
-		// Format the week as YYYY-MM-DD
-		weekStr := week.Week.Format("2006-01-02")
+		// Format the period start as YYYY-MM-DD
+		periodStr := periodStats.Start.Format("2006-01-02")
 
-		// Add rows for each author in this week
+		// Show periods without commits as a single empty row
+		if len(authors) == 0 {
+			row := []string{periodStr, "", "0", "0.0", "0"}
+			if showRework {
+				row = insertColumn(row, 3, "")
+			}
+			if stats.ClassifyLines {
+				row = insertColumn(row, 3, "0")
+			}
+			table.Append(append(row, repositoryColumns(stats, nil)...))
+		}
+
+		// Add rows for each author in this period
 		for i, author := range authors {
-			weekDisplay := ""
+			periodDisplay := ""
 			if i == 0 {
-				// Only show the week for the first author in each week
-				weekDisplay = weekStr
+				// Only show the period for the first author in each period
+				periodDisplay = periodStr
 			}
 
-			table.Append([]string{
-				weekDisplay,
+			row := []string{
+				periodDisplay,
 				author.Name,
 				fmt.Sprintf("%d", author.LinesChanged),
 				fmt.Sprintf("%.1f", float64(author.LinesChanged)),
 				fmt.Sprintf("%d", author.CommitCount),
-			})
+			}
+			if showRework {
+				row = insertColumn(row, 3, fmt.Sprintf("%.1f%%", reworkPercent(author.ReworkedLines, author.LinesAdded)))
+			}
+			if stats.ClassifyLines {
+				row = insertColumn(row, 3, fmt.Sprintf("%d", author.CodeLinesChanged))
+			}
+			table.Append(append(row, repositoryColumns(stats, author.Repositories)...))
 		}
 
-		// Add a separator between weeks
-		if len(authors) > 0 {
-			table.Append([]string{"", "", "", "", ""})
-		}
+		// Add a separator between periods
+		table.Append(make([]string, len(header)))
 	}
 
 	// Render the table
 	table.Render()
 }
+
+// insertColumn returns the row with value inserted at the given column
+func insertColumn(row []string, column int, value string) []string {
+	row = append(row, "")
+	copy(row[column+1:], row[column:])
+	row[column] = value
+	return row
+}
@@ -3,9 +3,9 @@ package main
 // Export types and functions for testing
 var (
 	// Functions
-	ExportedAnalyzeRepository = AnalyzeRepository
-	ExportedDisplayStats      = DisplayStats
-	ExportedDisplayWeeklyStats = DisplayWeeklyStats
+	ExportedAnalyzeRepository  = analyzeRepository
+	ExportedDisplayStats       = displayStats
+	ExportedDisplayWeeklyStats = displayWeeklyStats
 )
 
 // Export types for testing
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// fileRecord is the machine-readable form of a file churn row
+type fileRecord struct {
+	Path         string `json:"path"`
+	Commits      int    `json:"commits"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesDeleted int    `json:"lines_deleted"`
+	LinesChanged int    `json:"lines_changed"`
+	Authors      int    `json:"authors"`
+	MainAuthor   string `json:"main_author"`
+}
+
+// fileRecords builds the file churn rows, sorted by lines changed and then
+// by path, limited to the top files when top is positive
+func fileRecords(stats *RepositoryStats, top int) []fileRecord {
+	records := make([]fileRecord, 0, len(stats.Files))
+	for _, file := range stats.Files {
+		records = append(records, fileRecord{
+			Path:         file.Name,
+			Commits:      file.CommitCount,
+			LinesAdded:   file.LinesAdded,
+			LinesDeleted: file.LinesDeleted,
+			LinesChanged: file.LinesAdded + file.LinesDeleted,
+			Authors:      len(file.Authors),
+			MainAuthor:   mainAuthor(file.Authors),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].LinesChanged != records[j].LinesChanged {
+			return records[i].LinesChanged > records[j].LinesChanged
+		}
+		return records[i].Path < records[j].Path
+	})
+	if top > 0 && len(records) > top {
+		records = records[:top]
+	}
+	return records
+}
+
+// mainAuthor returns the author with the most commits, breaking ties by name
+func mainAuthor(commits map[string]int) string {
+	best := ""
+	for name, count := range commits {
+		if best == "" || count > commits[best] || (count == commits[best] && name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+// writeFiles writes the per-file churn in the requested format
+func writeFiles(stats *RepositoryStats, top int, format string) error {
+	records := fileRecords(stats, top)
+
+	switch format {
+	case FormatJSON:
+		return writeJSON(struct {
+			Files []fileRecord `json:"files"`
+		}{records})
+	case FormatCSV:
+		rows := [][]string{{"path", "commits", "lines_added", "lines_deleted", "lines_changed", "authors", "main_author"}}
+		for _, r := range records {
+			rows = append(rows, []string{
+				r.Path,
+				fmt.Sprintf("%d", r.Commits),
+				fmt.Sprintf("%d", r.LinesAdded),
+				fmt.Sprintf("%d", r.LinesDeleted),
+				fmt.Sprintf("%d", r.LinesChanged),
+				fmt.Sprintf("%d", r.Authors),
+				r.MainAuthor,
+			})
+		}
+		return writeCSV(rows)
+	default:
+		displayFiles(records)
+		return nil
+	}
+}
+
+// displayFiles displays the per-file churn in an ASCII table
+func displayFiles(records []fileRecord) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"File", "Commits", "Lines Added", "Lines Deleted", "Lines Changed", "Authors", "Main Author"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, r := range records {
+		table.Append([]string{
+			r.Path,
+			fmt.Sprintf("%d", r.Commits),
+			fmt.Sprintf("%d", r.LinesAdded),
+			fmt.Sprintf("%d", r.LinesDeleted),
+			fmt.Sprintf("%d", r.LinesChanged),
+			fmt.Sprintf("%d", r.Authors),
+			r.MainAuthor,
+		})
+	}
+	table.Render()
+}
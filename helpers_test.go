@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a throwaway git repository used by the tests
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+	wt   *git.Worktree
+}
+
+// newTestRepo initializes a git repository in a temporary directory
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+
+	// Skip this test if we're running in CI or don't want to create temp dirs
+	if os.Getenv("SKIP_REPO_TESTS") != "" {
+		t.Skip("Skipping test that requires creating a git repository")
+	}
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Failed to initialize git repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	return &testRepo{t: t, dir: dir, repo: repo, wt: wt}
+}
+
+// commit writes the given files, commits them as the given author and
+// returns the commit hash
+func (r *testRepo) commit(author string, when time.Time, files map[string]string) string {
+	r.t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(r.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			r.t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if _, err := r.wt.Add(name); err != nil {
+			r.t.Fatalf("Failed to add %s: %v", name, err)
+		}
+	}
+
+	signature := &object.Signature{Name: author, Email: author + "@example.com", When: when}
+	hash, err := r.wt.Commit("Commit by "+author, &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		r.t.Fatalf("Failed to commit as %s: %v", author, err)
+	}
+	return hash.String()
+}
+
+// newTestStats returns empty repository stats ready for analysis
+func newTestStats() *RepositoryStats {
+	return &RepositoryStats{
+		Authors:     make(map[string]*AuthorStats),
+		WeeklyStats: make(map[string]*WeeklyStats),
+		IgnoreFiles: make(map[string]bool),
+	}
+}
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+
+	originalStdout := os.Stdout
+	r, pipeWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = pipeWriter
+
+	// Read concurrently so large outputs do not fill the pipe
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+
+	err = fn()
+	pipeWriter.Close()
+	os.Stdout = originalStdout
+	if err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	return <-output
+}
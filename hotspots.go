@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/olekukonko/tablewriter"
+)
+
+// indentTabWidth is the number of spaces counted as one level of indentation
+const indentTabWidth = 4
+
+// Hotspot describes a file that is both complex and frequently changed
+type Hotspot struct {
+	Path       string
+	Directory  string
+	Revisions  int     // Commits that changed the file
+	Churn      int     // Lines added and deleted over the file's history
+	Lines      int     // Non-blank lines at HEAD
+	Complexity int     // Sum of the indentation depth of every non-blank line at HEAD
+	MaxDepth   int     // Deepest indentation level at HEAD
+	Score      float64 // Normalized revisions multiplied by normalized size (see hotspotSize)
+}
+
+// DirectoryHotspots summarizes the hotspots of a directory
+type DirectoryHotspots struct {
+	Directory string
+	Files     int
+	Revisions int
+	Score     float64 // Sum of the file scores
+}
+
+// indentationComplexity measures how deeply nested the lines of a file are.
+// It returns the number of non-blank lines, the sum of their indentation
+// levels and the deepest level found.
+func indentationComplexity(content string) (lines int, total int, maxDepth int) {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+
+		// Count leading tabs and spaces as indentation levels
+		spaces := 0
+		for _, r := range line {
+			if r == '\t' {
+				spaces += indentTabWidth
+			} else if r == ' ' {
+				spaces++
+			} else {
+				break
+			}
+		}
+		depth := spaces / indentTabWidth
+		total += depth
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	return lines, total, maxDepth
+}
+
+// AnalyzeHotspots ranks the files at HEAD by combining their change frequency,
+// taken from stats.Files, with their size and indentation complexity.
+// stats must already have been filled by analyzeRepository.
+func AnalyzeHotspots(repo *git.Repository, stats *RepositoryStats) ([]*Hotspot, error) {
+	// Get the HEAD commit
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	files, err := head.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	// Measure every file at HEAD that has a change history
+	hotspots := []*Hotspot{}
+	err = files.ForEach(func(f *object.File) error {
+		fileStats, ok := stats.Files[f.Name]
+		if !ok || !stats.IncludesFile(f.Name) {
+			return nil
+		}
+		if binary, err := f.IsBinary(); err != nil || binary {
+			return nil
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+
+		lines, complexity, maxDepth := indentationComplexity(content)
+		hotspots = append(hotspots, &Hotspot{
+			Path:       f.Name,
+			Directory:  path.Dir(f.Name),
+			Revisions:  fileStats.CommitCount,
+			Churn:      fileStats.LinesAdded + fileStats.LinesDeleted,
+			Lines:      lines,
+			Complexity: complexity,
+			MaxDepth:   maxDepth,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scoreHotspots(hotspots)
+	return hotspots, nil
+}
+
+// scoreHotspots scores and sorts hotspots. Revisions and size are normalized
+// against the largest values in the repository before being multiplied.
+func scoreHotspots(hotspots []*Hotspot) {
+	maxRevisions, maxSize := 0, 0
+	for _, h := range hotspots {
+		if h.Revisions > maxRevisions {
+			maxRevisions = h.Revisions
+		}
+		if size := hotspotSize(h); size > maxSize {
+			maxSize = size
+		}
+	}
+
+	for _, h := range hotspots {
+		if maxRevisions > 0 && maxSize > 0 {
+			h.Score = float64(h.Revisions) / float64(maxRevisions) * float64(hotspotSize(h)) / float64(maxSize)
+		}
+	}
+
+	sort.Slice(hotspots, func(i, j int) bool {
+		if hotspots[i].Score != hotspots[j].Score {
+			return hotspots[i].Score > hotspots[j].Score
+		}
+		return hotspots[i].Path < hotspots[j].Path
+	})
+}
+
+// hotspotSize returns the size measure used for scoring a hotspot: its
+// non-blank lines weighted by how deeply they are indented
+func hotspotSize(h *Hotspot) int {
+	return h.Lines + h.Complexity
+}
+
+// groupHotspots sums the hotspot scores per directory, highest score first
+func groupHotspots(hotspots []*Hotspot) []*DirectoryHotspots {
+	byDirectory := make(map[string]*DirectoryHotspots)
+	for _, h := range hotspots {
+		dir, ok := byDirectory[h.Directory]
+		if !ok {
+			dir = &DirectoryHotspots{Directory: h.Directory}
+			byDirectory[h.Directory] = dir
+		}
+		dir.Files++
+		dir.Revisions += h.Revisions
+		dir.Score += h.Score
+	}
+
+	directories := make([]*DirectoryHotspots, 0, len(byDirectory))
+	for _, dir := range byDirectory {
+		directories = append(directories, dir)
+	}
+	sort.Slice(directories, func(i, j int) bool {
+		if directories[i].Score != directories[j].Score {
+			return directories[i].Score > directories[j].Score
+		}
+		return directories[i].Directory < directories[j].Directory
+	})
+	return directories
+}
+
+// writeHotspots writes the hotspot report in the requested format, limited to
+// the top files when top is positive
+func writeHotspots(hotspots []*Hotspot, top int, format string) error {
+	directories := groupHotspots(hotspots)
+	if top > 0 && len(hotspots) > top {
+		hotspots = hotspots[:top]
+	}
+
+	switch format {
+	case FormatJSON:
+		type hotspotRecord struct {
+			Path       string  `json:"path"`
+			Directory  string  `json:"directory"`
+			Revisions  int     `json:"revisions"`
+			Churn      int     `json:"churn"`
+			Lines      int     `json:"lines"`
+			Complexity int     `json:"complexity"`
+			MaxDepth   int     `json:"max_depth"`
+			Score      float64 `json:"score"`
+		}
+		type directoryRecord struct {
+			Directory string  `json:"directory"`
+			Files     int     `json:"files"`
+			Revisions int     `json:"revisions"`
+			Score     float64 `json:"score"`
+		}
+		files := make([]hotspotRecord, 0, len(hotspots))
+		for _, h := range hotspots {
+			files = append(files, hotspotRecord(*h))
+		}
+		dirs := make([]directoryRecord, 0, len(directories))
+		for _, d := range directories {
+			dirs = append(dirs, directoryRecord(*d))
+		}
+		return writeJSON(struct {
+			Files       []hotspotRecord   `json:"files"`
+			Directories []directoryRecord `json:"directories"`
+		}{files, dirs})
+	case FormatCSV:
+		rows := [][]string{{"path", "directory", "revisions", "churn", "lines", "complexity", "max_depth", "score"}}
+		for _, h := range hotspots {
+			rows = append(rows, []string{
+				h.Path,
+				h.Directory,
+				fmt.Sprintf("%d", h.Revisions),
+				fmt.Sprintf("%d", h.Churn),
+				fmt.Sprintf("%d", h.Lines),
+				fmt.Sprintf("%d", h.Complexity),
+				fmt.Sprintf("%d", h.MaxDepth),
+				fmt.Sprintf("%.3f", h.Score),
+			})
+		}
+		return writeCSV(rows)
+	default:
+		displayHotspots(hotspots, directories)
+		return nil
+	}
+}
+
+// displayHotspots displays the hotspot ranking and directory summary in ASCII tables
+func displayHotspots(hotspots []*Hotspot, directories []*DirectoryHotspots) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"File", "Revisions", "Churn", "Lines", "Complexity", "Max Depth", "Score"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, h := range hotspots {
+		table.Append([]string{
+			h.Path,
+			fmt.Sprintf("%d", h.Revisions),
+			fmt.Sprintf("%d", h.Churn),
+			fmt.Sprintf("%d", h.Lines),
+			fmt.Sprintf("%d", h.Complexity),
+			fmt.Sprintf("%d", h.MaxDepth),
+			fmt.Sprintf("%.3f", h.Score),
+		})
+	}
+	table.Render()
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Directory", "Files", "Revisions", "Score"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, d := range directories {
+		table.Append([]string{
+			d.Directory,
+			fmt.Sprintf("%d", d.Files),
+			fmt.Sprintf("%d", d.Revisions),
+			fmt.Sprintf("%.3f", d.Score),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndentationComplexity(t *testing.T) {
+	content := "func main() {\n\tif x {\n\t\ty()\n\t}\n\n        z()\n}\n"
+	lines, total, maxDepth := indentationComplexity(content)
+
+	if lines != 6 {
+		t.Errorf("Expected 6 non-blank lines, got %d", lines)
+	}
+	// Depths: 0, 1, 2, 1, 2 (eight spaces), 0
+	if total != 6 {
+		t.Errorf("Expected a total depth of 6, got %d", total)
+	}
+	if maxDepth != 2 {
+		t.Errorf("Expected a max depth of 2, got %d", maxDepth)
+	}
+}
+
+func TestAnalyzeHotspots(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	nested := "func f() {\n\tif a {\n\t\tif b {\n\t\t\tc()\n\t\t}\n\t}\n}\n"
+	r.commit("Alice", start, map[string]string{"core/engine.go": nested, "docs/notes.md": "one\n"})
+	r.commit("Alice", start.AddDate(0, 0, 1), map[string]string{"core/engine.go": nested + "// v2\n"})
+	r.commit("Bob", start.AddDate(0, 0, 2), map[string]string{"core/engine.go": nested + "// v3\n"})
+	r.commit("Bob", start.AddDate(0, 0, 3), map[string]string{"docs/notes.md": "one\ntwo\n"})
+
+	stats := newTestStats()
+	if err := analyzeRepository(r.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if stats.Files["core/engine.go"].CommitCount != 3 {
+		t.Errorf("Expected 3 commits for core/engine.go, got %d", stats.Files["core/engine.go"].CommitCount)
+	}
+
+	hotspots, err := AnalyzeHotspots(r.repo, stats)
+	if err != nil {
+		t.Fatalf("Failed to analyze hotspots: %v", err)
+	}
+	if len(hotspots) != 2 {
+		t.Fatalf("Expected 2 hotspots, got %d", len(hotspots))
+	}
+
+	// The nested, frequently changed file ranks first with the maximum score
+	if hotspots[0].Path != "core/engine.go" || hotspots[0].Score != 1 {
+		t.Errorf("Expected core/engine.go to be the top hotspot, got %+v", hotspots[0])
+	}
+	if hotspots[0].MaxDepth != 3 || hotspots[0].Revisions != 3 {
+		t.Errorf("Unexpected measurements for core/engine.go: %+v", hotspots[0])
+	}
+
+	directories := groupHotspots(hotspots)
+	if len(directories) != 2 || directories[0].Directory != "core" {
+		t.Errorf("Expected core to be the top directory, got %+v", directories)
+	}
+}
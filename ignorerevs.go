@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// DefaultIgnoreRevsFile is the file git blame conventionally reads ignored revisions from
+const DefaultIgnoreRevsFile = ".git-blame-ignore-revs"
+
+// loadIgnoreRevs adds the commit hashes listed in an ignore-revs file to revs.
+// The file lists one full commit hash per line; everything after a # is a comment.
+func loadIgnoreRevs(filename string, revs map[string]bool) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" {
+			continue
+		}
+		if !plumbing.IsHash(line) {
+			return fmt.Errorf("%s:%d: %q is not a full commit hash", filename, lineNumber, line)
+		}
+		revs[line] = true
+	}
+	return scanner.Err()
+}
+
+// reattributeIgnoredLines credits lines blamed on ignored commits to the
+// commits that wrote them before. A line is matched with a line of the same
+// text, ignoring whitespace, in the file as it was before the ignored commit.
+// Lines without a match stay with the ignored commit.
+func reattributeIgnoredLines(repo *git.Repository, revs map[string]bool, filePath string, lines []*git.Line) ([]*git.Line, error) {
+	lines = append([]*git.Line(nil), lines...)
+
+	// The commit a line moves to may itself be ignored, so repeat until nothing changes
+	for round := 0; round <= len(revs); round++ {
+		ignored := make(map[plumbing.Hash][]int)
+		for i, line := range lines {
+			if revs[line.Hash.String()] {
+				ignored[line.Hash] = append(ignored[line.Hash], i)
+			}
+		}
+
+		changed := false
+		for hash, indexes := range ignored {
+			previous, err := blameBefore(repo, hash, filePath)
+			if err != nil {
+				return nil, err
+			}
+
+			// Hand out the earlier lines to the ignored lines with the same text
+			candidates := make(map[string][]*git.Line)
+			for _, line := range previous {
+				key := normalizeLine(line.Text)
+				candidates[key] = append(candidates[key], line)
+			}
+			for _, i := range indexes {
+				key := normalizeLine(lines[i].Text)
+				if matches := candidates[key]; len(matches) > 0 {
+					lines[i] = matches[0]
+					candidates[key] = matches[1:]
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// blameBefore blames a file as it was in the first parent of a commit. It
+// returns no lines when the commit has no parent or the file did not exist.
+func blameBefore(repo *git.Repository, hash plumbing.Hash, filePath string) ([]*git.Line, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if commit.NumParents() == 0 {
+		return nil, nil
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parent.File(filePath); err != nil {
+		return nil, nil
+	}
+
+	result, err := git.Blame(parent, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("blaming %s before %s: %w", filePath, hash, err)
+	}
+	return result.Lines, nil
+}
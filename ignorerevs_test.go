@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadIgnoreRevs(t *testing.T) {
+	hash := strings.Repeat("a1", 20)
+	filename := filepath.Join(t.TempDir(), DefaultIgnoreRevsFile)
+	content := "# Reformat with gofmt\n" + strings.ToUpper(hash) + "  # trailing comment\n\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	revs := make(map[string]bool)
+	if err := loadIgnoreRevs(filename, revs); err != nil {
+		t.Fatalf("Failed to load ignore-revs: %v", err)
+	}
+	if len(revs) != 1 || !revs[hash] {
+		t.Errorf("Expected %s to be ignored, got %v", hash, revs)
+	}
+
+	if err := os.WriteFile(filename, []byte("abc123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadIgnoreRevs(filename, revs); err == nil {
+		t.Errorf("Expected an error for an abbreviated hash")
+	}
+}
+
+func TestIgnoreRevs(t *testing.T) {
+	repo := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.commit("Alice", when, map[string]string{
+		"main.go": "package main\nfunc main() {\nprintln(1)\n}\n",
+	})
+	// Bob reindents the whole file
+	reformat := repo.commit("Bob", when.Add(time.Hour), map[string]string{
+		"main.go": "package main\nfunc main() {\n\tprintln(1)\n}\n",
+	})
+	repo.commit("Carol", when.Add(2*time.Hour), map[string]string{
+		"main.go": "package main\nfunc main() {\n\tprintln(1)\n\tprintln(2)\n}\n",
+	})
+
+	stats := newTestStats()
+	stats.IgnoreRevs = map[string]bool{reformat: true}
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if _, ok := stats.Authors["Bob"]; ok || stats.IgnoredCommits != 1 || stats.TotalCommits != 2 {
+		t.Errorf("Expected Bob's commit to be skipped, got %d commits and %d ignored", stats.TotalCommits, stats.IgnoredCommits)
+	}
+	if stats.Files["main.go"].CommitCount != 2 {
+		t.Errorf("Expected 2 commits changing main.go, got %d", stats.Files["main.go"].CommitCount)
+	}
+
+	// The reindented line goes back to Alice
+	ownership, err := AnalyzeOwnership(repo.repo, stats)
+	if err != nil {
+		t.Fatalf("Failed to analyze ownership: %v", err)
+	}
+	if ownership.Authors["Alice"] != 4 || ownership.Authors["Carol"] != 1 || ownership.Authors["Bob"] != 0 {
+		t.Errorf("Unexpected ownership: %v", ownership.Authors)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// knowledgeLossThreshold is the share of surviving lines departed authors must
+// have written for a file or directory to be at risk
+const knowledgeLossThreshold = 0.5
+
+// DepartedAuthor is an author considered to have left the project
+type DepartedAuthor struct {
+	Name       string
+	LastCommit time.Time // Latest analyzed commit or surviving line, whichever is later
+	Lines      int       // Surviving lines at HEAD
+}
+
+// KnowledgeLoss describes a file or directory mostly written by departed authors
+type KnowledgeLoss struct {
+	Path          string
+	TotalLines    int
+	DepartedLines int
+	Share         float64 // Fraction of the surviving lines written by departed authors
+	TopAuthor     string  // Departed author with the most surviving lines
+}
+
+// KnowledgeLossStats holds the code at HEAD that is mostly written by departed authors
+type KnowledgeLossStats struct {
+	Departed    []*DepartedAuthor
+	Files       []*KnowledgeLoss
+	Directories []*KnowledgeLoss
+	TotalLines  int
+	LostLines   int // Surviving lines written by departed authors
+}
+
+// lastActivity returns the latest activity of every author: their last
+// analyzed commit or the author date of their newest surviving line, whichever
+// is later, so authors known only from blame are included
+func lastActivity(stats *RepositoryStats, ownership *OwnershipStats) map[string]time.Time {
+	last := make(map[string]time.Time)
+	for name, author := range stats.Authors {
+		last[name] = author.LastCommit
+	}
+	for name, date := range ownership.LastChanged {
+		if date.After(last[name]) {
+			last[name] = date
+		}
+	}
+	return last
+}
+
+// analysisEnd returns the end of the analyzed date range: -until when given,
+// the present otherwise
+func analysisEnd(stats *RepositoryStats) time.Time {
+	if !stats.Until.IsZero() {
+		return stats.Until
+	}
+	return time.Now()
+}
+
+// departedAuthors returns the authors in names plus, when inactiveMonths is
+// positive, every author whose last activity is more than inactiveMonths
+// before end
+func departedAuthors(stats *RepositoryStats, ownership *OwnershipStats, names []string, inactiveMonths int, end time.Time) map[string]bool {
+	departed := make(map[string]bool)
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			departed[name] = true
+		}
+	}
+
+	if inactiveMonths > 0 {
+		cutoff := end.AddDate(0, -inactiveMonths, 0)
+		for name, last := range lastActivity(stats, ownership) {
+			if last.Before(cutoff) {
+				departed[name] = true
+			}
+		}
+	}
+	return departed
+}
+
+// CalculateKnowledgeLoss finds the files and directories at HEAD whose
+// surviving lines were mostly written by departed authors
+func CalculateKnowledgeLoss(stats *RepositoryStats, ownership *OwnershipStats, departed map[string]bool) *KnowledgeLossStats {
+	result := &KnowledgeLossStats{TotalLines: ownership.TotalLines}
+
+	last := lastActivity(stats, ownership)
+	for name := range departed {
+		author := &DepartedAuthor{Name: name, Lines: ownership.Authors[name], LastCommit: last[name]}
+		result.Departed = append(result.Departed, author)
+		result.LostLines += author.Lines
+	}
+	sort.Slice(result.Departed, func(i, j int) bool {
+		if result.Departed[i].Lines != result.Departed[j].Lines {
+			return result.Departed[i].Lines > result.Departed[j].Lines
+		}
+		return result.Departed[i].Name < result.Departed[j].Name
+	})
+
+	for _, file := range ownership.Files {
+		if loss := knowledgeLoss(file.Path, file.Lines, departed); loss != nil {
+			result.Files = append(result.Files, loss)
+		}
+	}
+	for dir, lines := range ownership.Directories {
+		if loss := knowledgeLoss(dir, lines, departed); loss != nil {
+			result.Directories = append(result.Directories, loss)
+		}
+	}
+	sortKnowledgeLoss(result.Files)
+	sortKnowledgeLoss(result.Directories)
+
+	return result
+}
+
+// knowledgeLoss returns the knowledge loss of a path from its surviving lines
+// per author, or nil when departed authors wrote no more than the threshold
+func knowledgeLoss(path string, lines map[string]int, departed map[string]bool) *KnowledgeLoss {
+	loss := &KnowledgeLoss{Path: path}
+	for name, count := range lines {
+		loss.TotalLines += count
+		if !departed[name] {
+			continue
+		}
+		loss.DepartedLines += count
+		if loss.TopAuthor == "" || count > lines[loss.TopAuthor] || (count == lines[loss.TopAuthor] && name < loss.TopAuthor) {
+			loss.TopAuthor = name
+		}
+	}
+	if loss.TotalLines == 0 {
+		return nil
+	}
+
+	loss.Share = float64(loss.DepartedLines) / float64(loss.TotalLines)
+	if loss.Share <= knowledgeLossThreshold {
+		return nil
+	}
+	return loss
+}
+
+// sortKnowledgeLoss orders losses by the lines written by departed authors
+func sortKnowledgeLoss(losses []*KnowledgeLoss) {
+	sort.Slice(losses, func(i, j int) bool {
+		if losses[i].DepartedLines != losses[j].DepartedLines {
+			return losses[i].DepartedLines > losses[j].DepartedLines
+		}
+		return losses[i].Path < losses[j].Path
+	})
+}
+
+// formatLastCommit formats a last commit date, or "-" when unknown
+func formatLastCommit(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+// writeKnowledgeLoss writes the knowledge-loss report in the requested format
+func writeKnowledgeLoss(loss *KnowledgeLossStats, format string) error {
+	switch format {
+	case FormatJSON:
+		type authorRecord struct {
+			Name       string `json:"name"`
+			LastCommit string `json:"last_commit,omitempty"`
+			Lines      int    `json:"surviving_lines"`
+		}
+		type lossRecord struct {
+			Path          string  `json:"path"`
+			TotalLines    int     `json:"total_lines"`
+			DepartedLines int     `json:"departed_lines"`
+			Share         float64 `json:"share"`
+			TopAuthor     string  `json:"top_author"`
+		}
+		records := func(losses []*KnowledgeLoss) []lossRecord {
+			result := []lossRecord{}
+			for _, l := range losses {
+				result = append(result, lossRecord(*l))
+			}
+			return result
+		}
+		authors := []authorRecord{}
+		for _, a := range loss.Departed {
+			lastCommit := ""
+			if !a.LastCommit.IsZero() {
+				lastCommit = a.LastCommit.Format(time.RFC3339)
+			}
+			authors = append(authors, authorRecord{a.Name, lastCommit, a.Lines})
+		}
+		return writeJSON(struct {
+			Departed    []authorRecord `json:"departed"`
+			TotalLines  int            `json:"total_lines"`
+			LostLines   int            `json:"departed_lines"`
+			Files       []lossRecord   `json:"files"`
+			Directories []lossRecord   `json:"directories"`
+		}{authors, loss.TotalLines, loss.LostLines, records(loss.Files), records(loss.Directories)})
+	case FormatCSV:
+		rows := [][]string{{"scope", "path", "total_lines", "departed_lines", "share", "top_author"}}
+		appendRows := func(scope string, losses []*KnowledgeLoss) {
+			for _, l := range losses {
+				rows = append(rows, []string{
+					scope,
+					l.Path,
+					fmt.Sprintf("%d", l.TotalLines),
+					fmt.Sprintf("%d", l.DepartedLines),
+					fmt.Sprintf("%.3f", l.Share),
+					l.TopAuthor,
+				})
+			}
+		}
+		appendRows("directory", loss.Directories)
+		appendRows("file", loss.Files)
+		return writeCSV(rows)
+	default:
+		displayKnowledgeLoss(loss)
+		return nil
+	}
+}
+
+// displayKnowledgeLoss displays the knowledge-loss report in ASCII tables
+func displayKnowledgeLoss(loss *KnowledgeLossStats) {
+	// Departed authors and the code they leave behind
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Departed Author", "Last Commit", "Surviving Lines", "Share %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, a := range loss.Departed {
+		table.Append([]string{
+			a.Name,
+			formatLastCommit(a.LastCommit),
+			fmt.Sprintf("%d", a.Lines),
+			fmt.Sprintf("%.1f%%", linePercent(a.Lines, loss.TotalLines)),
+		})
+	}
+	table.Append([]string{"TOTAL", "", fmt.Sprintf("%d", loss.LostLines), fmt.Sprintf("%.1f%%", linePercent(loss.LostLines, loss.TotalLines))})
+	table.Render()
+
+	// Directories and files mostly written by departed authors
+	for _, scope := range []struct {
+		header string
+		losses []*KnowledgeLoss
+	}{{"Directory", loss.Directories}, {"File", loss.Files}} {
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{scope.header, "Lines", "Departed Lines", "Departed %", "Top Departed Author"})
+		table.SetBorder(true)
+		table.SetAutoFormatHeaders(false)
+		for _, l := range scope.losses {
+			table.Append([]string{
+				l.Path,
+				fmt.Sprintf("%d", l.TotalLines),
+				fmt.Sprintf("%d", l.DepartedLines),
+				fmt.Sprintf("%.1f%%", l.Share*100),
+				l.TopAuthor,
+			})
+		}
+		table.Render()
+	}
+}
+
+// linePercent returns lines as a percentage of total
+func linePercent(lines, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(lines) / float64(total) * 100
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDepartedAuthors(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	stats := newTestStats()
+	stats.Authors["Alice"] = &AuthorStats{Name: "Alice", LastCommit: now.AddDate(0, -1, 0)}
+	stats.Authors["Bob"] = &AuthorStats{Name: "Bob", LastCommit: now.AddDate(0, -8, 0)}
+	stats.Authors["Carol"] = &AuthorStats{Name: "Carol", LastCommit: now.AddDate(0, -2, 0)}
+
+	// Dave has no commits in the analyzed range, only lines surviving at HEAD
+	ownership := &OwnershipStats{LastChanged: map[string]time.Time{
+		"Alice": now.AddDate(-1, 0, 0),
+		"Dave":  now.AddDate(-2, 0, 0),
+	}}
+
+	departed := departedAuthors(stats, ownership, []string{" Carol", ""}, 6, now)
+	if len(departed) != 3 || !departed["Bob"] || !departed["Carol"] || !departed["Dave"] {
+		t.Errorf("Expected Bob, Carol and Dave to have departed, got %v", departed)
+	}
+
+	// Without an inactivity period only the explicit list counts
+	departed = departedAuthors(stats, ownership, nil, 0, now)
+	if len(departed) != 0 {
+		t.Errorf("Expected no departed authors, got %v", departed)
+	}
+
+	// Inactivity is measured against the end of the analyzed range
+	stats.Until = now.AddDate(0, -4, 0)
+	departed = departedAuthors(stats, ownership, nil, 6, analysisEnd(stats))
+	if len(departed) != 1 || !departed["Dave"] {
+		t.Errorf("Expected only Dave to have departed by -until, got %v", departed)
+	}
+}
+
+func TestCalculateKnowledgeLoss(t *testing.T) {
+	last := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	stats := newTestStats()
+	stats.Authors["Bob"] = &AuthorStats{Name: "Bob", LastCommit: last}
+	ownership := &OwnershipStats{
+		Authors: map[string]int{"Alice": 60, "Bob": 90},
+		Directories: map[string]map[string]int{
+			"api": {"Alice": 10, "Bob": 90},
+			"web": {"Alice": 50},
+		},
+		Files: []*FileOwnership{
+			{Path: "api/a.go", Lines: map[string]int{"Alice": 10, "Bob": 70}, TotalLines: 80},
+			{Path: "api/b.go", Lines: map[string]int{"Bob": 20}, TotalLines: 20},
+			{Path: "web/c.ts", Lines: map[string]int{"Alice": 50}, TotalLines: 50},
+		},
+		TotalLines: 150,
+	}
+
+	loss := CalculateKnowledgeLoss(stats, ownership, map[string]bool{"Bob": true, "Dave": true})
+
+	if len(loss.Departed) != 2 || loss.Departed[0].Name != "Bob" || !loss.Departed[0].LastCommit.Equal(last) {
+		t.Errorf("Unexpected departed authors: %+v", loss.Departed)
+	}
+	if !loss.Departed[1].LastCommit.IsZero() || loss.Departed[1].Lines != 0 {
+		t.Errorf("Expected Dave to have no history, got %+v", loss.Departed[1])
+	}
+	if loss.LostLines != 90 || loss.TotalLines != 150 {
+		t.Errorf("Expected 90 of 150 lines lost, got %d of %d", loss.LostLines, loss.TotalLines)
+	}
+
+	if len(loss.Files) != 2 || loss.Files[0].Path != "api/a.go" || loss.Files[1].Path != "api/b.go" {
+		t.Fatalf("Unexpected files at risk: %+v", loss.Files)
+	}
+	if loss.Files[0].DepartedLines != 70 || loss.Files[0].TopAuthor != "Bob" {
+		t.Errorf("Unexpected loss for api/a.go: %+v", loss.Files[0])
+	}
+	if len(loss.Directories) != 1 || loss.Directories[0].Path != "api" || loss.Directories[0].Share != 0.9 {
+		t.Errorf("Unexpected directories at risk: %+v", loss.Directories)
+	}
+}
+
+func TestAggregateCommitTracksActivity(t *testing.T) {
+	first := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	stats := newTestStats()
+
+	// Commits arrive newest first from the log
+	for _, when := range []time.Time{last, first} {
+		aggregateCommit(stats, &CommitRecord{Author: "Alice", When: when, Files: []FileChange{{Name: "a.go", Additions: 1}}})
+	}
+
+	alice := stats.Authors["Alice"]
+	if !alice.FirstCommit.Equal(first) || !alice.LastCommit.Equal(last) {
+		t.Errorf("Expected activity from %s to %s, got %s to %s", first, last, alice.FirstCommit, alice.LastCommit)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// otherLanguage is the language of files that could not be classified
+const otherLanguage = "Other"
+
+// languageExtensions maps lower-case file extensions to languages
+var languageExtensions = map[string]string{
+	".go":     "Go",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".rs":     "Rust",
+	".php":    "PHP",
+	".pl":     "Perl",
+	".pm":     "Perl",
+	".lua":    "Lua",
+	".r":      "R",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".hs":     "Haskell",
+	".clj":    "Clojure",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".sass":   "SCSS",
+	".less":   "Less",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".json":   "JSON",
+	".yaml":   "YAML",
+	".yml":    "YAML",
+	".toml":   "TOML",
+	".xml":    "XML",
+	".md":     "Markdown",
+	".proto":  "Protocol Buffers",
+	".tf":     "HCL",
+	".hcl":    "HCL",
+}
+
+// languageFilenames maps well-known file names to languages
+var languageFilenames = map[string]string{
+	"Makefile":       "Makefile",
+	"GNUmakefile":    "Makefile",
+	"Dockerfile":     "Dockerfile",
+	"Containerfile":  "Dockerfile",
+	"CMakeLists.txt": "CMake",
+	"Rakefile":       "Ruby",
+	"Gemfile":        "Ruby",
+	"Vagrantfile":    "Ruby",
+	"Jenkinsfile":    "Groovy",
+	"BUILD":          "Starlark",
+	"BUILD.bazel":    "Starlark",
+	"WORKSPACE":      "Starlark",
+	"go.mod":         "Go Module",
+}
+
+// languageInterpreters maps shebang interpreters to languages
+var languageInterpreters = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"dash":    "Shell",
+	"ksh":     "Shell",
+	"python":  "Python",
+	"node":    "JavaScript",
+	"deno":    "TypeScript",
+	"ruby":    "Ruby",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"lua":     "Lua",
+	"Rscript": "R",
+	"pwsh":    "PowerShell",
+}
+
+// languageFromName classifies a file by its name and extension. It returns
+// an empty string when the name is not recognized.
+func languageFromName(filename string) string {
+	base := path.Base(filename)
+	if language, ok := languageFilenames[base]; ok {
+		return language
+	}
+	if strings.HasPrefix(base, "Dockerfile.") {
+		return "Dockerfile"
+	}
+	return languageExtensions[strings.ToLower(path.Ext(base))]
+}
+
+// languageFromShebang classifies a script by the interpreter named on its
+// "#!" line. It returns an empty string when there is no known interpreter.
+func languageFromShebang(firstLine string) string {
+	if !strings.HasPrefix(firstLine, "#!") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Look past env and its options: "#!/usr/bin/env -S node --flags"
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+
+	// Drop version suffixes such as python3 or python3.11
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return languageInterpreters[interpreter]
+}
+
+// LanguageDetector classifies files by language, falling back to the shebang
+// line of files whose name is not recognized. Results are cached per path.
+type LanguageDetector struct {
+	repo  *git.Repository
+	cache map[string]string
+}
+
+// NewLanguageDetector creates a detector reading shebang lines from repo.
+// With a nil repo only file names are used.
+func NewLanguageDetector(repo *git.Repository) *LanguageDetector {
+	return &LanguageDetector{
+		repo:  repo,
+		cache: make(map[string]string),
+	}
+}
+
+// Language returns the language of a file as it exists in the given commit
+func (d *LanguageDetector) Language(hash string, filename string) string {
+	if language, ok := d.cache[filename]; ok {
+		return language
+	}
+
+	language := languageFromName(filename)
+	if language == "" {
+		language = d.shebangLanguage(hash, filename)
+	}
+	if language == "" {
+		language = otherLanguage
+	}
+	d.cache[filename] = language
+	return language
+}
+
+// shebangLanguage reads the first line of a file in a commit and classifies
+// it by its shebang. A file deleted by the commit is read from its parent.
+func (d *LanguageDetector) shebangLanguage(hash string, filename string) string {
+	if d.repo == nil || hash == "" {
+		return ""
+	}
+	commit, err := d.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return ""
+	}
+	file, err := commit.File(filename)
+	if err != nil && commit.NumParents() > 0 {
+		var parent *object.Commit
+		if parent, err = commit.Parent(0); err == nil {
+			file, err = parent.File(filename)
+		}
+	}
+	if err != nil {
+		return ""
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	firstLine, _ := bufio.NewReader(reader).ReadString('\n')
+	return languageFromShebang(strings.TrimSpace(firstLine))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestLanguageFromName(t *testing.T) {
+	tests := map[string]string{
+		"main.go":               "Go",
+		"web/src/App.TSX":       "TypeScript",
+		"lib/util.js":           "JavaScript",
+		"Makefile":              "Makefile",
+		"build/Dockerfile.dev":  "Dockerfile",
+		"tools/CMakeLists.txt":  "CMake",
+		"scripts/deploy":        "",
+		"docs/notes.unknownext": "",
+	}
+	for filename, expected := range tests {
+		if language := languageFromName(filename); language != expected {
+			t.Errorf("Expected %s to be %q, got %q", filename, expected, language)
+		}
+	}
+}
+
+func TestLanguageFromShebang(t *testing.T) {
+	tests := map[string]string{
+		"#!/bin/sh":                            "Shell",
+		"#!/usr/bin/env python3":               "Python",
+		"#!/usr/bin/env -S node --no-warnings": "JavaScript",
+		"#!/usr/local/bin/ruby -w":             "Ruby",
+		"#!/usr/bin/env":                       "",
+		"package main":                         "",
+	}
+	for line, expected := range tests {
+		if language := languageFromShebang(line); language != expected {
+			t.Errorf("Expected %q to be %q, got %q", line, expected, language)
+		}
+	}
+}
+
+func TestLanguageDetectorShebang(t *testing.T) {
+	repo := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.commit("Alice", when, map[string]string{
+		"scripts/deploy": "#!/usr/bin/env bash\necho deploy\n",
+		"LICENSE":        "MIT\n",
+	})
+
+	stats := newTestStats()
+	detector := NewLanguageDetector(repo.repo)
+	languages := make(map[string]string)
+	err := WalkCommits(repo.repo, stats, func(record *CommitRecord) error {
+		for _, change := range record.Files {
+			languages[change.Name] = detector.Language(record.Hash, change.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+
+	if languages["scripts/deploy"] != "Shell" || languages["LICENSE"] != otherLanguage {
+		t.Errorf("Unexpected languages: %v", languages)
+	}
+
+	// A deleted file is read from the parent of the deleting commit, and cached
+	if _, err := repo.wt.Remove("scripts/deploy"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "Bob", Email: "bob@example.com", When: when.Add(time.Hour)}
+	deletion, err := repo.wt.Commit("Remove deploy script", &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector = NewLanguageDetector(repo.repo)
+	if language := detector.Language(deletion.String(), "scripts/deploy"); language != "Shell" {
+		t.Errorf("Expected the deleted script to be Shell, got %s", language)
+	}
+	if language, ok := detector.cache["scripts/deploy"]; !ok || language != "Shell" {
+		t.Errorf("Expected the language of the deleted script to be cached, got %q", language)
+	}
+}
+
+func TestComponentCollectorByLanguage(t *testing.T) {
+	stats := newTestStats()
+	grouping, err := parseGroupBy("language", nil)
+	if err != nil {
+		t.Fatalf("Failed to parse grouping: %v", err)
+	}
+	collector := NewComponentCollector(grouping, stats)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	collector.Add(&CommitRecord{Author: "Alice", When: when, Files: []FileChange{{Name: "api/a.go", Additions: 10}, {Name: "api/b.go", Additions: 4}}})
+	collector.Add(&CommitRecord{Author: "Bob", When: when, Files: []FileChange{{Name: "web/app.ts", Additions: 7}, {Name: "api/a.go", Additions: 1}}})
+
+	golang, typescript := collector.Components["Go"], collector.Components["TypeScript"]
+	if golang == nil || typescript == nil || len(collector.Components) != 2 {
+		t.Fatalf("Unexpected languages: %v", collector.Names())
+	}
+	if golang.Authors["Alice"].LinesChanged != 14 || golang.Authors["Bob"].LinesChanged != 1 || golang.TotalCommits != 2 {
+		t.Errorf("Unexpected Go stats: %+v %+v", golang.Authors["Alice"], golang.Authors["Bob"])
+	}
+	if typescript.Authors["Bob"].LinesChanged != 7 || typescript.Authors["Alice"] != nil {
+		t.Errorf("Unexpected TypeScript authors: %v", typescript.Authors)
+	}
+}
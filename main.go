@@ -1,85 +1,499 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	_ "time/tzdata" // Embed the time zone database so -tz works everywhere
 
 	"github.com/go-git/go-git/v5"
 )
 
 func main() {
-	// Define command-line flags
-	ignoreFilesFlag := flag.String("ignore", "", "Comma-separated list of additional files to ignore")
-	fileFilterFlag := flag.String("ext", "", "File extension filter (e.g., .js, .go)")
-	weeklyFlag := flag.Bool("weekly", false, "Show weekly code frequency statistics")
-	
-	// Parse command-line arguments
-	flag.Parse()
-	args := flag.Args()
+	// Parse the command line
+	inv, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(2)
+	}
+	cmd, opts := inv.Command, inv.Options
 
+	// Resolve the repositories; several are analyzed together, with the
+	// configuration of the current directory
+	repoPaths, err := expandRepositories(inv.RepoPaths, opts.Repos)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
 	repoPath := "."
-	fileFilter := *fileFilterFlag
+	if len(repoPaths) == 1 {
+		repoPath = repoPaths[0]
+	}
 
-	if len(args) > 0 {
-		// Check if the first argument is a file extension (starts with a dot)
-		if strings.HasPrefix(args[0], ".") {
-			fileFilter = args[0]
-		} else {
-			repoPath = args[0]
-			
-			// Check if there's a second argument for file extension
-			if len(args) > 1 && strings.HasPrefix(args[1], ".") {
-				fileFilter = args[1]
-			}
+	// Load the user and repository configuration; flags override its settings
+	config, err := loadConfigs(repoPath)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %s\n", err)
+		os.Exit(1)
+	}
+	if err := config.Apply(inv.Flags); err != nil {
+		fmt.Printf("Error in configuration: %s\n", err)
+		os.Exit(1)
+	}
+	if inv.ShowConfig {
+		if err := writeConfig(os.Stdout, config); err != nil {
+			fmt.Printf("Error writing configuration: %s\n", err)
+			os.Exit(1)
 		}
+		return
 	}
 
-	// Open the repository
-	repo, err := git.PlainOpen(repoPath)
+	if err := validateFormat(opts.Format); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	report := cmd.Name
+	if report != "authors" && report != "weekly" && opts.Format == FormatNDJSON {
+		fmt.Printf("Error: the %s command does not support ndjson output\n", report)
+		os.Exit(1)
+	}
+
+	// Resolve the time series period; -weekly is shorthand for -period=week
+	period := PeriodWeek
+	if opts.Period != "" {
+		var err error
+		period, err = parsePeriod(opts.Period)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	showPeriods := opts.Weekly || opts.Period != "" || report == "weekly"
+
+	weekStart, err := parseWeekStart(opts.WeekStart)
 	if err != nil {
-		fmt.Printf("Error opening repository: %s\n", err)
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	botMode, err := parseBotMode(opts.Bots)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if opts.Merges != "include" && opts.Merges != "exclude" {
+		fmt.Printf("Error: unsupported merge policy %q (expected include or exclude)\n", opts.Merges)
 		os.Exit(1)
 	}
 
+	// Load the teams authors are rolled up into; a teams file replaces the configured teams
+	var teams *TeamMap
+	var tagOrder TagOrder
+	switch opts.By {
+	case "author":
+	case "tag":
+		if tagOrder, err = parseTagOrder(opts.TagOrder); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		if showPeriods || len(repoPaths) > 1 || opts.RecurseSubmodules || opts.Format == FormatNDJSON || opts.Components != "" || opts.GroupBy != "" {
+			fmt.Printf("Error: -by=tag takes a single repository and does not support time series, ndjson output, -group-by or -components\n")
+			os.Exit(1)
+		}
+	case "team":
+		if opts.Teams != "" {
+			if teams, err = loadTeams(opts.Teams); err != nil {
+				fmt.Printf("Error loading teams: %s\n", err)
+				os.Exit(1)
+			}
+		} else if teams = config.TeamMap(); teams == nil {
+			fmt.Printf("Error: -by=team requires a teams file (-teams) or teams in %s\n", ConfigFile)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Error: unsupported aggregation %q (expected author, team or tag)\n", opts.By)
+		os.Exit(1)
+	}
+
+	var location *time.Location
+	if opts.Timezone != "" {
+		location, err = time.LoadLocation(opts.Timezone)
+		if err != nil {
+			fmt.Printf("Error loading time zone: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse the date range; -until includes the whole day
+	var since, until time.Time
+	if opts.Since != "" {
+		if since, err = parseDate(opts.Since, location); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.Until != "" {
+		if until, err = parseDate(opts.Until, location); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		until = until.AddDate(0, 0, 1)
+	}
+
 	// Initialize repository stats
 	stats := &RepositoryStats{
 		Authors:     make(map[string]*AuthorStats),
 		WeeklyStats: make(map[string]*WeeklyStats),
-		FileFilter:  fileFilter,
+		Periods:     make(map[string]*PeriodStats),
+		Period:      period,
+		WeekStart:   weekStart,
+		Location:    location,
+		Since:       since,
+		Until:       until,
+		FileFilter:  opts.Ext,
 		IgnoreFiles: make(map[string]bool),
+		IgnoreRevs:  make(map[string]bool),
+		Bots:        botMode,
+		BotAuthors:  parseBotAuthors(opts.BotAuthors),
+		ByTeam:      teams != nil,
+		Aliases:     config.Aliases,
+		SkipMerges:  opts.Merges == "exclude",
+		IgnoreGlobs: config.Ignore,
+	}
+	stats.ClassifyLines = opts.CodeLines || opts.SkipNonCode
+	stats.SkipNonCode = opts.SkipNonCode
+	stats.IgnoreWhitespace = opts.IgnoreWhitespace
+	if opts.Rework {
+		stats.ReworkWindow = time.Duration(opts.ReworkWindow) * 24 * time.Hour
 	}
 
-	// Load .gitignore patterns
-	loadGitignore(repoPath, stats)
+	// Add user-specified files to ignore; globs come from the configuration
+	if opts.Ignore != "" {
+		for _, file := range strings.Split(opts.Ignore, ",") {
+			stats.IgnoreFiles[strings.TrimSpace(file)] = true
+		}
+	}
 
-	// Add common files to ignore
-	for _, file := range CommonIgnoreFiles {
-		stats.IgnoreFiles[file] = true
+	// Compare two ranges of the history side by side
+	if report == "compare" {
+		runCompare(repoPath, stats, inv.Ranges, opts)
+		return
 	}
-	
-	// Add user-specified files to ignore
-	if *ignoreFilesFlag != "" {
-		userIgnoreFiles := strings.Split(*ignoreFilesFlag, ",")
-		for _, file := range userIgnoreFiles {
-			stats.IgnoreFiles[strings.TrimSpace(file)] = true
+
+	// Break the statistics down by release
+	if tagOrder != "" {
+		runReleases(repoPath, stats, tagOrder, opts)
+		return
+	}
+
+	// Combine the statistics of several repositories, or of a repository and its submodules
+	paths := repoPaths
+	if len(paths) == 0 {
+		paths = []string{repoPath}
+	}
+	targets := repositoryTargets(paths)
+	if opts.RecurseSubmodules {
+		if targets, err = withSubmodules(targets, stats, os.Stderr); err != nil {
+			fmt.Printf("Error reading submodules: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(targets) > 1 {
+		runRepositories(targets, stats, teams, opts, showPeriods)
+		return
+	}
+
+	// Open the repository
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		fmt.Printf("Error opening repository: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Resolve the component grouping; a components file takes precedence
+	var grouping *Grouping
+	if opts.Components != "" {
+		if grouping, err = loadComponents(opts.Components); err != nil {
+			fmt.Printf("Error loading components: %s\n", err)
+			os.Exit(1)
+		}
+	} else if opts.GroupBy != "" {
+		if grouping, err = parseGroupBy(opts.GroupBy, repo); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
 		}
 	}
 
+	// Load the files and commits to leave out
+	ignoreRevsFile, err := loadRepositoryIgnores(stats, repoPath, opts.IgnoreRevs)
+	if err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+
+	// Show a progress bar on interactive terminals
+	var bar *progressBar
+	if shouldShowProgress(opts.Format) {
+		bar = newProgressBar(os.Stderr)
+		stats.OnProgress = bar.Update
+	}
+
+	// Stream per-commit records instead of aggregating them
+	if opts.Format == FormatNDJSON {
+		if err := WalkCommits(repo, stats, newNDJSONWriter()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing repository: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Collect report data while the commits are walked
+	var collectors []CommitFunc
+	var rework *ReworkTracker
+	if opts.Rework {
+		rework = NewReworkTracker(stats)
+		collectors = append(collectors, rework.Add)
+	}
+	var coupling *CouplingCollector
+	var survival *SurvivalCollector
+	switch report {
+	case "coupling":
+		coupling = NewCouplingCollector(CouplingOptions{
+			MinRevisions:      opts.MinRevisions,
+			MaxFilesPerCommit: opts.MaxCommitFiles,
+		})
+		collectors = append(collectors, coupling.Add)
+	case "age":
+		survival = NewSurvivalCollector(stats)
+		collectors = append(collectors, survival.Add)
+	}
+
+	var components *ComponentCollector
+	if grouping != nil {
+		components = NewComponentCollector(grouping, stats)
+		collectors = append(collectors, components.Add)
+	}
+
 	// Get repository statistics
-	err = analyzeRepository(repo, stats)
+	err = WalkCommits(repo, stats, func(record *CommitRecord) error {
+		// Credit the commit to the author's team
+		if teams != nil {
+			record = teams.Apply(record)
+		}
+
+		aggregateCommit(stats, record)
+		for _, collect := range collectors {
+			if err := collect(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if bar != nil {
+		bar.Finish()
+	}
 	if err != nil {
 		fmt.Printf("Error analyzing repository: %s\n", err)
 		os.Exit(1)
 	}
+	if rework != nil {
+		rework.Finish()
+	}
+
+	// Fill in periods without commits across the analyzed range
+	if showPeriods && opts.Dense {
+		from, to := since, until
+		if !to.IsZero() {
+			to = to.Add(-time.Nanosecond)
+		}
+		fillPeriods(stats.Periods, stats.Period, stats.WeekStart, from, to)
+		if periods := sortedPeriods(stats.Periods); components != nil && len(periods) > 0 {
+			// Give every component the same range as the repository
+			for _, componentStats := range components.Components {
+				fillPeriods(componentStats.Periods, stats.Period, stats.WeekStart, periods[0].Start, periods[len(periods)-1].Start)
+			}
+		}
+	}
 
 	// Display statistics
-	if *weeklyFlag {
-		displayWeeklyStats(stats)
+	if report == "ownership" || report == "truckfactor" || report == "knowledgeloss" {
+		var ownership *OwnershipStats
+		ownership, err = AnalyzeOwnership(repo, stats)
+		if err != nil {
+			fmt.Printf("Error analyzing ownership: %s\n", err)
+			os.Exit(1)
+		}
+		if report == "truckfactor" {
+			err = writeTruckFactor(CalculateTruckFactor(ownership), opts.Format)
+		} else if report == "knowledgeloss" {
+			var names []string
+			if opts.Departed != "" {
+				names = strings.Split(opts.Departed, ",")
+			}
+			departed := departedAuthors(stats, ownership, names, opts.InactiveMonths, analysisEnd(stats))
+			err = writeKnowledgeLoss(CalculateKnowledgeLoss(stats, ownership, departed), opts.Format)
+		} else {
+			err = writeOwnership(stats, ownership, opts.Format)
+		}
+	} else if report == "coupling" {
+		err = writeCoupling(coupling, opts.Format)
+	} else if report == "hotspots" {
+		var hotspots []*Hotspot
+		hotspots, err = AnalyzeHotspots(repo, stats)
+		if err == nil {
+			err = writeHotspots(hotspots, opts.Top, opts.Format)
+		}
+	} else if report == "files" {
+		err = writeFiles(stats, opts.Top, opts.Format)
+	} else if report == "age" {
+		var age *CodeAgeStats
+		age, err = AnalyzeCodeAge(repo, stats, survival)
+		if err == nil {
+			err = writeCodeAge(age, opts.Format)
+		}
+	} else if components != nil {
+		err = writeComponents(stats, components, showPeriods, opts.Format)
+	} else if showPeriods {
+		err = writePeriodStats(stats, stats.Periods, stats.Period, opts.Format)
 	} else {
-		displayStats(stats)
+		err = writeStats(stats, opts.Format)
+	}
+	if err != nil {
+		fmt.Printf("Error writing output: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Report the ignored commits without mixing the note into machine-readable output
+	if stats.IgnoredCommits > 0 {
+		out := os.Stdout
+		if isMachineFormat(opts.Format) {
+			out = os.Stderr
+		}
+		fmt.Fprintf(out, "Skipped %d commits listed in %s\n", stats.IgnoredCommits, ignoreRevsFile)
+	}
+}
+
+// runRepositories analyzes several repositories in parallel and writes their
+// combined author statistics or time series
+func runRepositories(targets []repositoryTarget, settings *RepositoryStats, teams *TeamMap, opts *options, showPeriods bool) {
+	if opts.Format == FormatNDJSON || opts.Components != "" || opts.GroupBy != "" {
+		fmt.Printf("Error: ndjson output, -group-by and -components support a single repository without submodules\n")
+		os.Exit(1)
+	}
+
+	stats, err := analyzeRepositories(targets, settings, opts.IgnoreRevs, func(repo *git.Repository, stats *RepositoryStats) error {
+		var rework *ReworkTracker
+		if stats.ReworkWindow > 0 {
+			rework = NewReworkTracker(stats)
+		}
+		err := WalkCommits(repo, stats, func(record *CommitRecord) error {
+			// Credit the commit to the author's team
+			if teams != nil {
+				record = teams.Apply(record)
+			}
+
+			aggregateCommit(stats, record)
+			if rework != nil {
+				return rework.Add(record)
+			}
+			return nil
+		})
+		if err == nil && rework != nil {
+			rework.Finish()
+		}
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error analyzing repositories: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Fill in periods without commits across the analyzed range
+	if showPeriods && opts.Dense {
+		from, to := stats.Since, stats.Until
+		if !to.IsZero() {
+			to = to.Add(-time.Nanosecond)
+		}
+		fillPeriods(stats.Periods, stats.Period, stats.WeekStart, from, to)
+	}
+
+	if showPeriods {
+		err = writePeriodStats(stats, stats.Periods, stats.Period, opts.Format)
+	} else {
+		err = writeStats(stats, opts.Format)
+	}
+	if err != nil {
+		fmt.Printf("Error writing output: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Report the ignored commits without mixing the note into machine-readable output
+	if stats.IgnoredCommits > 0 {
+		out := os.Stdout
+		if isMachineFormat(opts.Format) {
+			out = os.Stderr
+		}
+		fmt.Fprintf(out, "Skipped %d commits listed in ignore-revs files\n", stats.IgnoredCommits)
+	}
+}
+
+// runCompare analyzes two ranges of a repository and writes the changes in
+// author statistics between them
+func runCompare(repoPath string, settings *RepositoryStats, ranges []string, opts *options) {
+	var sides [2]comparisonRange
+	for i, spec := range ranges {
+		var err error
+		if sides[i], err = parseComparisonRange(spec, settings.Location); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		fmt.Printf("Error opening repository: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := loadRepositoryIgnores(settings, repoPath, opts.IgnoreRevs); err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+
+	comparison, err := CompareRanges(repo, settings, sides[0], sides[1])
+	if err != nil {
+		fmt.Printf("Error comparing ranges: %s\n", err)
+		os.Exit(1)
+	}
+	if err := writeComparison(comparison, opts.Format); err != nil {
+		fmt.Printf("Error writing output: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// runReleases analyzes the commits between consecutive tags of a repository
+// and writes the statistics of each release
+func runReleases(repoPath string, settings *RepositoryStats, order TagOrder, opts *options) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		fmt.Printf("Error opening repository: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := loadRepositoryIgnores(settings, repoPath, opts.IgnoreRevs); err != nil {
+		fmt.Printf("Error %s\n", err)
+		os.Exit(1)
+	}
+
+	releases, err := AnalyzeReleases(repo, settings, order)
+	if err != nil {
+		fmt.Printf("Error analyzing releases: %s\n", err)
+		os.Exit(1)
+	}
+	if err := writeReleases(releases, opts.Format); err != nil {
+		fmt.Printf("Error writing output: %s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// expandRepositories resolves the repositories to analyze from the command
+// line arguments and an optional manifest file. Arguments and manifest lines
+// may be globs, which match directories only. Manifest lines are relative to
+// the manifest; blank lines and lines starting with # are ignored.
+func expandRepositories(args []string, manifest string) ([]string, error) {
+	patterns := append([]string(nil), args...)
+	if manifest != "" {
+		file, err := os.Open(manifest)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(filepath.Dir(manifest), line)
+			}
+			patterns = append(patterns, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			globbed, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pattern, err)
+			}
+			matches = matches[:0]
+			for _, match := range globbed {
+				if info, err := os.Stat(match); err == nil && info.IsDir() {
+					matches = append(matches, match)
+				}
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no matching directories", pattern)
+			}
+		}
+		for _, path := range matches {
+			if clean := filepath.Clean(path); !seen[clean] {
+				seen[clean] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// repositoryLabels returns the column label of each repository: the name of
+// its directory, or the path as given when two directories share a name
+func repositoryLabels(paths []string) []string {
+	names := make([]string, len(paths))
+	count := make(map[string]int)
+	for i, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		names[i] = filepath.Base(path)
+		count[names[i]]++
+	}
+	for i, name := range names {
+		if count[name] > 1 {
+			names[i] = filepath.Clean(paths[i])
+		}
+	}
+	return names
+}
+
+// newRepositoryStats returns empty statistics with the settings of another
+func newRepositoryStats(settings *RepositoryStats) *RepositoryStats {
+	stats := *settings
+	stats.Authors = make(map[string]*AuthorStats)
+	stats.WeeklyStats = make(map[string]*WeeklyStats)
+	stats.Periods = make(map[string]*PeriodStats)
+	stats.Files = nil
+	stats.IgnoreFiles = make(map[string]bool)
+	for file := range settings.IgnoreFiles {
+		stats.IgnoreFiles[file] = true
+	}
+	stats.IgnoreRevs = make(map[string]bool)
+	for rev := range settings.IgnoreRevs {
+		stats.IgnoreRevs[rev] = true
+	}
+	stats.IgnoredCommits = 0
+	stats.TotalCommits = 0
+	stats.TotalLines = 0
+	stats.TotalCodeLines = 0
+	stats.OnProgress = nil
+	return &stats
+}
+
+// loadRepositoryIgnores adds the files and commits a repository leaves out to
+// its statistics: its .gitignore patterns, the common dependency files and the
+// ignore-revs file, which is optional unless given explicitly. It returns the
+// ignore-revs file that was used.
+func loadRepositoryIgnores(stats *RepositoryStats, repoPath, ignoreRevs string) (string, error) {
+	loadGitignore(repoPath, stats)
+	for _, file := range CommonIgnoreFiles {
+		stats.IgnoreFiles[file] = true
+	}
+
+	ignoreRevsFile := ignoreRevs
+	if ignoreRevsFile == "" {
+		ignoreRevsFile = filepath.Join(repoPath, DefaultIgnoreRevsFile)
+	}
+	if err := loadIgnoreRevs(ignoreRevsFile, stats.IgnoreRevs); err != nil && (ignoreRevs != "" || !os.IsNotExist(err)) {
+		return ignoreRevsFile, fmt.Errorf("loading ignored revisions: %w", err)
+	}
+	return ignoreRevsFile, nil
+}
+
+// RepositoryAnalyzer analyzes one repository into its own statistics
+type RepositoryAnalyzer func(repo *git.Repository, stats *RepositoryStats) error
+
+// repositoryTarget is a repository to analyze as part of combined statistics
+type repositoryTarget struct {
+	Path         string
+	Label        string    // Column label in the combined statistics
+	Since, Until time.Time // Date range replacing the one in the settings, when set
+}
+
+// repositoryTargets returns the targets for repositories given by path
+func repositoryTargets(paths []string) []repositoryTarget {
+	labels := repositoryLabels(paths)
+	targets := make([]repositoryTarget, len(paths))
+	for i, path := range paths {
+		targets[i] = repositoryTarget{Path: path, Label: labels[i]}
+	}
+	return targets
+}
+
+// analyzeRepositories analyzes several repositories in parallel, each into
+// statistics with the given settings, and combines the results. Authors
+// sharing an email address in any repository are merged under one name, and
+// the lines changed by each author are also counted per repository.
+func analyzeRepositories(targets []repositoryTarget, settings *RepositoryStats, ignoreRevs string, analyze RepositoryAnalyzer) (*RepositoryStats, error) {
+	results := make([]*RepositoryStats, len(targets))
+	errs := make([]error, len(targets))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target repositoryTarget) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			repo, err := git.PlainOpen(target.Path)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			stats := newRepositoryStats(settings)
+			if !target.Since.IsZero() || !target.Until.IsZero() {
+				stats.Since, stats.Until = target.Since, target.Until
+			}
+			if _, err := loadRepositoryIgnores(stats, target.Path, ignoreRevs); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = analyze(repo, stats)
+			results[i] = stats
+		}(i, target)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", targets[i].Path, err)
+		}
+	}
+
+	combined := newRepositoryStats(settings)
+	names := map[string]string{}
+	if !settings.ByTeam {
+		names = unifyIdentities(results)
+	}
+	for i, stats := range results {
+		combined.Repositories = append(combined.Repositories, targets[i].Label)
+		mergeRepositoryStats(combined, stats, targets[i].Label, names)
+	}
+	return combined, nil
+}
+
+// unifyIdentities maps author names to one canonical name per person. Names
+// that committed with the same email address, in any of the repositories,
+// belong to the same person, whose canonical name is the one with the most
+// commits. Shared addresses, see sharedEmail, do not tie names together.
+func unifyIdentities(repos []*RepositoryStats) map[string]string {
+	parent := make(map[string]string)
+	var find func(name string) string
+	find = func(name string) string {
+		if parent[name] == name {
+			return name
+		}
+		parent[name] = find(parent[name])
+		return parent[name]
+	}
+
+	commits := make(map[string]int)
+	owners := make(map[string]string) // Email to the first name seen with it
+	for _, stats := range repos {
+		for name, author := range stats.Authors {
+			if _, ok := parent[name]; !ok {
+				parent[name] = name
+			}
+			commits[name] += author.CommitCount
+			for email := range author.Emails {
+				if sharedEmail(stats, email) {
+					continue
+				}
+				if owner, ok := owners[email]; ok {
+					parent[find(name)] = find(owner)
+				} else {
+					owners[email] = name
+				}
+			}
+		}
+	}
+
+	// Pick the name with the most commits, then the first alphabetically
+	canonical := make(map[string]string)
+	for name := range parent {
+		root := find(name)
+		best, ok := canonical[root]
+		if !ok || commits[name] > commits[best] || (commits[name] == commits[best] && name < best) {
+			canonical[root] = name
+		}
+	}
+	names := make(map[string]string, len(parent))
+	for name := range parent {
+		names[name] = canonical[find(name)]
+	}
+	return names
+}
+
+// sharedPlaceholders lists the lower-case local parts of addresses that
+// unrelated people commit with, such as noreply@github.com or root@localhost
+var sharedPlaceholders = map[string]bool{
+	"noreply":  true,
+	"no-reply": true,
+	"root":     true,
+	"nobody":   true,
+	"git":      true,
+	"ci":       true,
+	"build":    true,
+}
+
+// sharedEmail reports whether an email address may be used by several people:
+// empty and placeholder addresses, addresses at localhost and those of bots
+// and CI accounts
+func sharedEmail(stats *RepositoryStats, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, _ := strings.Cut(email, "@")
+	if local == "" || sharedPlaceholders[local] || knownBots[local] {
+		return true
+	}
+	if domain == "" || domain == "localhost" || strings.HasPrefix(domain, "localhost.") || domain == "(none)" {
+		return true
+	}
+	return isBot(stats, "", email)
+}
+
+// canonicalName returns the unified name of an author
+func canonicalName(names map[string]string, name string) string {
+	if canonical, ok := names[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// mergeRepositoryStats adds the statistics of one repository to the combined
+// statistics, renaming authors to their unified names. Files are prefixed with
+// the repository label.
+func mergeRepositoryStats(dst, src *RepositoryStats, label string, names map[string]string) {
+	for _, author := range src.Authors {
+		name := canonicalName(names, author.Name)
+		merged, ok := dst.Authors[name]
+		if !ok {
+			merged = &AuthorStats{Name: name, Emails: make(map[string]int), Repositories: make(map[string]int)}
+			dst.Authors[name] = merged
+		}
+		addAuthorTotals(merged, author)
+		if merged.FirstCommit.IsZero() || (!author.FirstCommit.IsZero() && author.FirstCommit.Before(merged.FirstCommit)) {
+			merged.FirstCommit = author.FirstCommit
+		}
+		if author.LastCommit.After(merged.LastCommit) {
+			merged.LastCommit = author.LastCommit
+		}
+		merged.Bot = merged.Bot || author.Bot
+		for email, count := range author.Emails {
+			merged.Emails[email] += count
+		}
+		merged.Repositories[label] += author.LinesChanged
+	}
+
+	dst.TotalCommits += src.TotalCommits
+	dst.TotalLines += src.TotalLines
+	dst.TotalCodeLines += src.TotalCodeLines
+	dst.IgnoredCommits += src.IgnoredCommits
+	mergePeriods(dst.Periods, src.Periods, label, names)
+	mergePeriods(dst.WeeklyStats, src.WeeklyStats, label, names)
+
+	if len(src.Files) > 0 && dst.Files == nil {
+		dst.Files = make(map[string]*FileStats)
+	}
+	for _, file := range src.Files {
+		merged := &FileStats{
+			Name:         label + "/" + file.Name,
+			CommitCount:  file.CommitCount,
+			LinesAdded:   file.LinesAdded,
+			LinesDeleted: file.LinesDeleted,
+			Authors:      make(map[string]int),
+		}
+		for name, count := range file.Authors {
+			merged.Authors[canonicalName(names, name)] += count
+		}
+		dst.Files[merged.Name] = merged
+	}
+}
+
+// mergePeriods adds the time series of one repository to a combined series
+func mergePeriods(dst, src map[string]*PeriodStats, label string, names map[string]string) {
+	for key, periodStats := range src {
+		merged, ok := dst[key]
+		if !ok {
+			merged = &PeriodStats{
+				Key:     key,
+				Start:   periodStats.Start,
+				Week:    periodStats.Start,
+				Authors: make(map[string]*PeriodAuthorStats),
+			}
+			dst[key] = merged
+		}
+		merged.TotalCommits += periodStats.TotalCommits
+		merged.TotalLines += periodStats.TotalLines
+
+		for _, author := range periodStats.Authors {
+			name := canonicalName(names, author.Name)
+			mergedAuthor, ok := merged.Authors[name]
+			if !ok {
+				mergedAuthor = &PeriodAuthorStats{Name: name, Start: author.Start, Week: author.Start, Repositories: make(map[string]int)}
+				merged.Authors[name] = mergedAuthor
+			}
+			mergedAuthor.CommitCount += author.CommitCount
+			mergedAuthor.LinesChanged += author.LinesChanged
+			mergedAuthor.LinesAdded += author.LinesAdded
+			mergedAuthor.ReworkedLines += author.ReworkedLines
+			mergedAuthor.CodeLinesChanged += author.CodeLinesChanged
+			mergedAuthor.Repositories[label] += author.LinesChanged
+		}
+	}
+}
+
+// repositoryColumns formats lines changed per repository in column order
+func repositoryColumns(stats *RepositoryStats, lines map[string]int) []string {
+	columns := make([]string, 0, len(stats.Repositories))
+	for _, label := range stats.Repositories {
+		columns = append(columns, fmt.Sprintf("%d", lines[label]))
+	}
+	return columns
+}
+
+// repositoryHeaders returns the CSV headers of the per-repository columns
+func repositoryHeaders(stats *RepositoryStats) []string {
+	headers := make([]string, 0, len(stats.Repositories))
+	for _, label := range stats.Repositories {
+		headers = append(headers, "lines_changed_"+label)
+	}
+	return headers
+}
+
+// repositoryTotals returns the lines changed in each repository
+func repositoryTotals(stats *RepositoryStats) map[string]int {
+	totals := make(map[string]int)
+	for _, author := range stats.Authors {
+		for label, lines := range author.Repositories {
+			totals[label] += lines
+		}
+	}
+	return totals
+}
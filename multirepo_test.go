@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestExpandRepositories(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"api", "web", "docs"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "repos.txt")
+	if err := os.WriteFile(manifest, []byte("# Services\napi\n\ndocs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := expandRepositories([]string{filepath.Join(dir, "*")}, "")
+	if err != nil {
+		t.Fatalf("Failed to expand glob: %v", err)
+	}
+	expected := []string{filepath.Join(dir, "api"), filepath.Join(dir, "docs"), filepath.Join(dir, "web")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected directories %v, got %v", expected, paths)
+	}
+
+	paths, err = expandRepositories([]string{filepath.Join(dir, "web"), filepath.Join(dir, "api")}, manifest)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	expected = []string{filepath.Join(dir, "web"), filepath.Join(dir, "api"), filepath.Join(dir, "docs")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected arguments then manifest entries without duplicates %v, got %v", expected, paths)
+	}
+
+	if _, err := expandRepositories([]string{filepath.Join(dir, "missing-*")}, ""); err == nil {
+		t.Errorf("Expected an error for a glob without matches")
+	}
+}
+
+func TestRepositoryLabels(t *testing.T) {
+	labels := repositoryLabels([]string{"/src/api", "/src/web/", "/old/api"})
+	expected := []string{"/src/api", "web", "/old/api"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, labels)
+	}
+}
+
+func TestUnifyIdentities(t *testing.T) {
+	api := newTestStats()
+	api.Authors["Alice Smith"] = &AuthorStats{Name: "Alice Smith", CommitCount: 5, Emails: map[string]int{"alice@example.com": 5}}
+	api.Authors["Bob"] = &AuthorStats{Name: "Bob", CommitCount: 2, Emails: map[string]int{"bob@example.com": 2}}
+	web := newTestStats()
+	web.Authors["asmith"] = &AuthorStats{Name: "asmith", CommitCount: 3, Emails: map[string]int{"alice@example.com": 1, "alice@laptop": 2}}
+	web.Authors["Alice"] = &AuthorStats{Name: "Alice", CommitCount: 1, Emails: map[string]int{"alice@laptop": 1}}
+	web.Authors["Unknown"] = &AuthorStats{Name: "Unknown", CommitCount: 1, Emails: map[string]int{"": 1}}
+	web.Authors["Nobody"] = &AuthorStats{Name: "Nobody", CommitCount: 1, Emails: map[string]int{"": 1}}
+
+	// Shared placeholder and CI addresses do not tie different people together
+	for name, email := range map[string]string{
+		"Carol": "noreply@github.com", "Dave": "noreply@github.com",
+		"Erin": "root@localhost", "Frank": "root@localhost",
+		"Grace": "ci-bot@example.com", "Heidi": "ci-bot@example.com",
+		"Ivan": "jenkins@ci.example.com", "Judy": "jenkins@ci.example.com",
+	} {
+		web.Authors[name] = &AuthorStats{Name: name, CommitCount: 1, Emails: map[string]int{email: 1}}
+	}
+	// GitHub's per-user noreply addresses still belong to one person
+	api.Authors["Bobby"] = &AuthorStats{Name: "Bobby", CommitCount: 1, Emails: map[string]int{"42+bob@users.noreply.github.com": 1}}
+	web.Authors["Bob"] = &AuthorStats{Name: "Bob", CommitCount: 1, Emails: map[string]int{"42+bob@users.noreply.github.com": 1}}
+
+	names := unifyIdentities([]*RepositoryStats{api, web})
+	for _, name := range []string{"Alice Smith", "asmith", "Alice"} {
+		if names[name] != "Alice Smith" {
+			t.Errorf("Expected %s to be unified as Alice Smith, got %q", name, names[name])
+		}
+	}
+	for _, name := range []string{"Unknown", "Nobody", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy"} {
+		if names[name] != name {
+			t.Errorf("Expected %s to keep their name, got %q", name, names[name])
+		}
+	}
+	if names["Bob"] != "Bob" || names["Bobby"] != "Bob" {
+		t.Errorf("Expected Bobby to be unified as Bob, got %q", names["Bobby"])
+	}
+}
+
+func TestAnalyzeRepositories(t *testing.T) {
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for _, name := range []string{"Alice Smith", "asmith"} {
+		r := newTestRepo(t)
+		r.commit("bob", when, map[string]string{"README.md": "Hello\n"})
+
+		// The same person commits under a different name in each repository
+		if err := os.WriteFile(filepath.Join(r.dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.wt.Add("main.go"); err != nil {
+			t.Fatal(err)
+		}
+		signature := &object.Signature{Name: name, Email: "alice@example.com", When: when.AddDate(0, 0, 7)}
+		if _, err := r.wt.Commit("Add main", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, r.dir)
+	}
+
+	settings := newTestStats()
+	settings.Periods = make(map[string]*PeriodStats)
+	stats, err := analyzeRepositories(repositoryTargets(paths), settings, "", func(repo *git.Repository, stats *RepositoryStats) error {
+		return analyzeRepository(repo, stats)
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze repositories: %v", err)
+	}
+
+	if stats.TotalCommits != 4 || len(stats.Authors) != 2 {
+		t.Fatalf("Expected 4 commits by 2 authors, got %d by %v", stats.TotalCommits, stats.Authors)
+	}
+	alice := stats.Authors["Alice Smith"]
+	if alice == nil || alice.CommitCount != 2 || alice.LinesChanged != 4 {
+		t.Fatalf("Expected Alice Smith with 2 commits and 4 lines, got %+v", alice)
+	}
+	if len(alice.Repositories) != 2 || alice.Repositories[stats.Repositories[0]] != 2 || alice.Repositories[stats.Repositories[1]] != 2 {
+		t.Errorf("Expected 2 lines in each repository, got %v", alice.Repositories)
+	}
+	if len(stats.WeeklyStats) != 2 || len(stats.Periods) != 2 {
+		t.Errorf("Expected two weeks in the combined time series, got %d", len(stats.WeeklyStats))
+	}
+	for _, week := range stats.WeeklyStats {
+		for _, author := range week.Authors {
+			if author.Name == "asmith" {
+				t.Errorf("Expected the time series to use unified names")
+			}
+		}
+	}
+	if len(stats.Files) != 4 {
+		t.Errorf("Expected the files of both repositories, got %d", len(stats.Files))
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Supported output formats
+const (
+	FormatTable  = "table"
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// isMachineFormat reports whether the format is meant for other programs
+// rather than for people reading a terminal
+func isMachineFormat(format string) bool {
+	return format != "" && format != FormatTable
+}
+
+// validateFormat checks that the given output format is supported
+func validateFormat(format string) error {
+	switch format {
+	case "", FormatTable, FormatJSON, FormatCSV, FormatNDJSON:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// authorRecord is the machine-readable form of an author row
+type authorRecord struct {
+	Author              string   `json:"author"`
+	Commits             int      `json:"commits"`
+	LinesChanged        int      `json:"lines_changed"`
+	LinesChangedPercent float64  `json:"lines_changed_percent"`
+	CommitsPercent      float64  `json:"commits_percent"`
+	CodeLinesChanged    *int     `json:"code_lines_changed,omitempty"`
+	ReworkedLines       *int     `json:"reworked_lines,omitempty"`
+	ReworkPercent       *float64 `json:"rework_percent,omitempty"`
+	Bot                 bool     `json:"bot,omitempty"`
+
+	Repositories map[string]int `json:"repositories,omitempty"` // Lines changed per repository
+}
+
+// periodRecord is the machine-readable form of a time series author row
+type periodRecord struct {
+	Period           string   `json:"period"`
+	Start            string   `json:"start"`
+	Author           string   `json:"author"`
+	LinesChanged     int      `json:"lines_changed"`
+	Commits          int      `json:"commits"`
+	CodeLinesChanged *int     `json:"code_lines_changed,omitempty"`
+	ReworkedLines    *int     `json:"reworked_lines,omitempty"`
+	ReworkPercent    *float64 `json:"rework_percent,omitempty"`
+
+	Repositories map[string]int `json:"repositories,omitempty"` // Lines changed per repository
+}
+
+// authorRecords converts the author statistics into sorted records
+func authorRecords(stats *RepositoryStats) []authorRecord {
+	authors := sortedAuthors(stats)
+	records := make([]authorRecord, 0, len(authors))
+	for _, author := range authors {
+		record := authorRecord{
+			Author:       author.Name,
+			Commits:      author.CommitCount,
+			LinesChanged: author.LinesChanged,
+			Bot:          author.Bot && stats.Bots == BotsSeparate,
+			Repositories: author.Repositories,
+		}
+		if stats.TotalLines > 0 {
+			record.LinesChangedPercent = float64(author.LinesChanged) / float64(stats.TotalLines) * 100
+		}
+		if stats.TotalCommits > 0 {
+			record.CommitsPercent = float64(author.CommitCount) / float64(stats.TotalCommits) * 100
+		}
+		record.CodeLinesChanged = codeLinesField(stats, author.CodeLinesChanged)
+		record.ReworkedLines, record.ReworkPercent = reworkFields(stats, author.ReworkedLines, author.LinesAdded)
+		records = append(records, record)
+	}
+	return records
+}
+
+// codeLinesField returns the code lines of a record, or nil when lines are not classified
+func codeLinesField(stats *RepositoryStats, codeLines int) *int {
+	if !stats.ClassifyLines {
+		return nil
+	}
+	return &codeLines
+}
+
+// reworkFields returns the rework values of a record, or nils when rework is not tracked
+func reworkFields(stats *RepositoryStats, reworked, added int) (*int, *float64) {
+	if stats.ReworkWindow <= 0 {
+		return nil, nil
+	}
+	percent := reworkPercent(reworked, added)
+	return &reworked, &percent
+}
+
+// periodRecords converts a time series into sorted records
+func periodRecords(stats *RepositoryStats, series map[string]*PeriodStats) []periodRecord {
+	records := []periodRecord{}
+	for _, periodStats := range sortedPeriods(series) {
+		// Keep periods without commits so consumers can plot gaps
+		if len(periodStats.Authors) == 0 {
+			records = append(records, periodRecord{
+				Period: periodStats.Key,
+				Start:  periodStats.Start.Format("2006-01-02"),
+			})
+		}
+
+		for _, author := range sortedPeriodAuthors(periodStats) {
+			record := periodRecord{
+				Period:       periodStats.Key,
+				Start:        periodStats.Start.Format("2006-01-02"),
+				Author:       author.Name,
+				LinesChanged: author.LinesChanged,
+				Commits:      author.CommitCount,
+				Repositories: author.Repositories,
+			}
+			record.CodeLinesChanged = codeLinesField(stats, author.CodeLinesChanged)
+			record.ReworkedLines, record.ReworkPercent = reworkFields(stats, author.ReworkedLines, author.LinesAdded)
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// writeStats writes the author statistics in the requested format
+func writeStats(stats *RepositoryStats, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(struct {
+			Authors      []authorRecord `json:"authors"`
+			TotalCommits int            `json:"total_commits"`
+			TotalLines   int            `json:"total_lines"`
+			TotalCode    *int           `json:"total_code_lines,omitempty"`
+		}{authorRecords(stats), stats.TotalCommits, stats.TotalLines, codeLinesField(stats, stats.TotalCodeLines)})
+	case FormatCSV:
+		header := []string{"author", "commits", "lines_changed", "lines_changed_percent", "commits_percent"}
+		if stats.ClassifyLines {
+			header = append(header, "code_lines_changed")
+		}
+		if stats.ReworkWindow > 0 {
+			header = append(header, "reworked_lines", "rework_percent")
+		}
+		if stats.Bots == BotsSeparate {
+			header = append(header, "bot")
+		}
+		header = append(header, repositoryHeaders(stats)...)
+		rows := [][]string{header}
+		for _, r := range authorRecords(stats) {
+			row := []string{
+				r.Author,
+				fmt.Sprintf("%d", r.Commits),
+				fmt.Sprintf("%d", r.LinesChanged),
+				fmt.Sprintf("%.1f", r.LinesChangedPercent),
+				fmt.Sprintf("%.1f", r.CommitsPercent),
+			}
+			if stats.ClassifyLines {
+				row = append(row, formatOptionalInt(r.CodeLinesChanged))
+			}
+			if stats.ReworkWindow > 0 {
+				row = append(row, formatOptionalInt(r.ReworkedLines), formatOptionalPercent(r.ReworkPercent))
+			}
+			if stats.Bots == BotsSeparate {
+				row = append(row, fmt.Sprintf("%t", r.Bot))
+			}
+			rows = append(rows, append(row, repositoryColumns(stats, r.Repositories)...))
+		}
+		return writeCSV(rows)
+	default:
+		displayStats(stats)
+		return nil
+	}
+}
+
+// writePeriodStats writes a time series in the requested format
+func writePeriodStats(stats *RepositoryStats, series map[string]*PeriodStats, period Period, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(struct {
+			Period  Period         `json:"period"`
+			Periods []periodRecord `json:"periods"`
+		}{period, periodRecords(stats, series)})
+	case FormatCSV:
+		header := []string{"period", "start", "author", "lines_changed", "commits"}
+		if stats.ClassifyLines {
+			header = append(header, "code_lines_changed")
+		}
+		if stats.ReworkWindow > 0 {
+			header = append(header, "reworked_lines", "rework_percent")
+		}
+		header = append(header, repositoryHeaders(stats)...)
+		rows := [][]string{header}
+		for _, r := range periodRecords(stats, series) {
+			row := []string{
+				r.Period,
+				r.Start,
+				r.Author,
+				fmt.Sprintf("%d", r.LinesChanged),
+				fmt.Sprintf("%d", r.Commits),
+			}
+			if stats.ClassifyLines {
+				row = append(row, formatOptionalInt(r.CodeLinesChanged))
+			}
+			if stats.ReworkWindow > 0 {
+				row = append(row, formatOptionalInt(r.ReworkedLines), formatOptionalPercent(r.ReworkPercent))
+			}
+			rows = append(rows, append(row, repositoryColumns(stats, r.Repositories)...))
+		}
+		return writeCSV(rows)
+	default:
+		if stats.ByTeam {
+			displayPeriodMatrix(stats, series, period)
+			return nil
+		}
+		displayPeriodStats(stats, series, period)
+		return nil
+	}
+}
+
+// newNDJSONWriter returns a CommitFunc that writes each commit record to
+// stdout as a single line of JSON
+func newNDJSONWriter() CommitFunc {
+	encoder := json.NewEncoder(os.Stdout)
+	return func(record *CommitRecord) error {
+		return encoder.Encode(record)
+	}
+}
+
+// formatOptionalInt formats an optional integer for CSV output
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *value)
+}
+
+// formatOptionalPercent formats an optional percentage for CSV output
+func formatOptionalPercent(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", *value)
+}
+
+// writeJSON writes a value to stdout as indented JSON
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeCSV writes rows to stdout as CSV
+func writeCSV(rows [][]string) error {
+	writer := csv.NewWriter(os.Stdout)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// sortedAuthors returns the authors sorted by commit count (descending)
+func sortedAuthors(stats *RepositoryStats) []*AuthorStats {
+	authors := make([]*AuthorStats, 0, len(stats.Authors))
+	for _, author := range stats.Authors {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].CommitCount != authors[j].CommitCount {
+			return authors[i].CommitCount > authors[j].CommitCount
+		}
+		return authors[i].Name < authors[j].Name
+	})
+	return authors
+}
+
+// sortedPeriods returns the periods of a time series sorted by date (ascending)
+func sortedPeriods(series map[string]*PeriodStats) []*PeriodStats {
+	periods := make([]*PeriodStats, 0, len(series))
+	for _, periodStats := range series {
+		periods = append(periods, periodStats)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods
+}
+
+// sortedPeriodAuthors returns the authors of a period sorted by lines changed (descending)
+func sortedPeriodAuthors(periodStats *PeriodStats) []*PeriodAuthorStats {
+	authors := make([]*PeriodAuthorStats, 0, len(periodStats.Authors))
+	for _, author := range periodStats.Authors {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].LinesChanged != authors[j].LinesChanged {
+			return authors[i].LinesChanged > authors[j].LinesChanged
+		}
+		return authors[i].Name < authors[j].Name
+	})
+	return authors
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/olekukonko/tablewriter"
+)
+
+// FileOwnership holds the surviving lines per author for a single file at HEAD
+type FileOwnership struct {
+	Path       string
+	Lines      map[string]int // Surviving lines per author
+	TotalLines int
+}
+
+// OwnershipStats holds the current code ownership at HEAD, based on blame
+type OwnershipStats struct {
+	Authors     map[string]int            // Surviving lines per author
+	Directories map[string]map[string]int // Surviving lines per author in each directory and its subdirectories
+	Files       []*FileOwnership
+	TotalLines  int
+	LastChanged map[string]time.Time // Author date of the newest surviving line per author
+}
+
+// BlameFunc is called by blameFiles with the blamed lines of a file at HEAD
+type BlameFunc func(path string, lines []*git.Line) error
+
+// blameFiles runs blame over every file at HEAD that passes the FileFilter and
+// IgnoreFiles settings in stats. Binary files are skipped, and lines last
+// changed by commits in stats.IgnoreRevs are credited to earlier commits.
+func blameFiles(repo *git.Repository, stats *RepositoryStats, fn BlameFunc) error {
+	// Get the HEAD commit
+	ref, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	files, err := head.Files()
+	if err != nil {
+		return err
+	}
+
+	return files.ForEach(func(f *object.File) error {
+		if !stats.IncludesFile(f.Name) {
+			return nil
+		}
+		if binary, err := f.IsBinary(); err != nil || binary {
+			return nil
+		}
+
+		result, err := git.Blame(head, f.Name)
+		if err != nil {
+			return fmt.Errorf("blaming %s: %w", f.Name, err)
+		}
+
+		lines := result.Lines
+		if len(stats.IgnoreRevs) > 0 {
+			if lines, err = reattributeIgnoredLines(repo, stats.IgnoreRevs, f.Name, lines); err != nil {
+				return err
+			}
+		}
+		return fn(f.Name, lines)
+	})
+}
+
+// commitAuthors resolves commit hashes to author names, caching the lookups
+type commitAuthors struct {
+	repo    *git.Repository
+	aliases map[string]string
+	names   map[plumbing.Hash]string
+}
+
+// newCommitAuthors creates an author lookup for the given repository that
+// resolves author aliases
+func newCommitAuthors(repo *git.Repository, aliases map[string]string) *commitAuthors {
+	return &commitAuthors{
+		repo:    repo,
+		aliases: aliases,
+		names:   make(map[plumbing.Hash]string),
+	}
+}
+
+// Name returns the canonical author name of the given commit
+func (a *commitAuthors) Name(hash plumbing.Hash) (string, error) {
+	if name, ok := a.names[hash]; ok {
+		return name, nil
+	}
+	commit, err := a.repo.CommitObject(hash)
+	if err != nil {
+		return "", err
+	}
+	name := resolveAlias(a.aliases, commit.Author.Name, commit.Author.Email)
+	a.names[hash] = name
+	return name, nil
+}
+
+// AnalyzeOwnership computes how many lines at HEAD were last written by each author
+func AnalyzeOwnership(repo *git.Repository, stats *RepositoryStats) (*OwnershipStats, error) {
+	ownership := &OwnershipStats{
+		Authors:     make(map[string]int),
+		Directories: make(map[string]map[string]int),
+		LastChanged: make(map[string]time.Time),
+	}
+	authors := newCommitAuthors(repo, stats.Aliases)
+
+	err := blameFiles(repo, stats, func(filePath string, lines []*git.Line) error {
+		file := &FileOwnership{
+			Path:  filePath,
+			Lines: make(map[string]int),
+		}
+
+		// Credit every surviving line to the author of the commit that last changed it
+		for _, line := range lines {
+			name, err := authors.Name(line.Hash)
+			if err != nil {
+				return err
+			}
+			file.Lines[name]++
+			file.TotalLines++
+			if line.Date.After(ownership.LastChanged[name]) {
+				ownership.LastChanged[name] = line.Date
+			}
+		}
+
+		// Roll the file up into every directory above it and the repository totals
+		for _, dir := range ancestorDirectories(filePath) {
+			if ownership.Directories[dir] == nil {
+				ownership.Directories[dir] = make(map[string]int)
+			}
+			for name, count := range file.Lines {
+				ownership.Directories[dir][name] += count
+			}
+		}
+		for name, count := range file.Lines {
+			ownership.Authors[name] += count
+		}
+		ownership.TotalLines += file.TotalLines
+		ownership.Files = append(ownership.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ownership, nil
+}
+
+// ancestorDirectories returns the directories containing a file, from its own
+// directory up to the repository root "."
+func ancestorDirectories(filePath string) []string {
+	var dirs []string
+	for dir := path.Dir(filePath); ; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == "." || dir == "/" {
+			return dirs
+		}
+	}
+}
+
+// ownershipRecord is the machine-readable form of an ownership row
+type ownershipRecord struct {
+	Directory           string  `json:"directory,omitempty"`
+	Author              string  `json:"author"`
+	SurvivingLines      int     `json:"surviving_lines"`
+	OwnershipPercent    float64 `json:"ownership_percent"`
+	LinesChanged        int     `json:"lines_changed"`
+	LinesChangedPercent float64 `json:"lines_changed_percent"`
+}
+
+// ownershipRecords builds the overall ownership rows, sorted by surviving lines,
+// with the historical lines changed of each author alongside
+func ownershipRecords(stats *RepositoryStats, ownership *OwnershipStats) []ownershipRecord {
+	// Include authors that only appear in one of the two views
+	names := make(map[string]bool)
+	for name := range ownership.Authors {
+		names[name] = true
+	}
+	for name := range stats.Authors {
+		names[name] = true
+	}
+
+	records := make([]ownershipRecord, 0, len(names))
+	for name := range names {
+		record := ownershipRecord{
+			Author:         name,
+			SurvivingLines: ownership.Authors[name],
+		}
+		if ownership.TotalLines > 0 {
+			record.OwnershipPercent = float64(record.SurvivingLines) / float64(ownership.TotalLines) * 100
+		}
+		if author, ok := stats.Authors[name]; ok {
+			record.LinesChanged = author.LinesChanged
+			if stats.TotalLines > 0 {
+				record.LinesChangedPercent = float64(author.LinesChanged) / float64(stats.TotalLines) * 100
+			}
+		}
+		records = append(records, record)
+	}
+
+	sortOwnershipRecords(records)
+	return records
+}
+
+// directoryOwnershipRecords builds the per-directory ownership rows
+func directoryOwnershipRecords(ownership *OwnershipStats) []ownershipRecord {
+	records := []ownershipRecord{}
+	for dir, authors := range ownership.Directories {
+		total := 0
+		for _, count := range authors {
+			total += count
+		}
+		for name, count := range authors {
+			record := ownershipRecord{
+				Directory:      dir,
+				Author:         name,
+				SurvivingLines: count,
+			}
+			if total > 0 {
+				record.OwnershipPercent = float64(count) / float64(total) * 100
+			}
+			records = append(records, record)
+		}
+	}
+
+	sortOwnershipRecords(records)
+	return records
+}
+
+// sortOwnershipRecords sorts by directory, then by surviving lines (descending)
+func sortOwnershipRecords(records []ownershipRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Directory != records[j].Directory {
+			return records[i].Directory < records[j].Directory
+		}
+		if records[i].SurvivingLines != records[j].SurvivingLines {
+			return records[i].SurvivingLines > records[j].SurvivingLines
+		}
+		return records[i].Author < records[j].Author
+	})
+}
+
+// writeOwnership writes the ownership report in the requested format
+func writeOwnership(stats *RepositoryStats, ownership *OwnershipStats, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(struct {
+			Authors     []ownershipRecord `json:"authors"`
+			Directories []ownershipRecord `json:"directories"`
+			TotalLines  int               `json:"total_lines"`
+		}{ownershipRecords(stats, ownership), directoryOwnershipRecords(ownership), ownership.TotalLines})
+	case FormatCSV:
+		rows := [][]string{{"directory", "author", "surviving_lines", "ownership_percent", "lines_changed", "lines_changed_percent"}}
+		for _, r := range append(ownershipRecords(stats, ownership), directoryOwnershipRecords(ownership)...) {
+			rows = append(rows, []string{
+				r.Directory,
+				r.Author,
+				fmt.Sprintf("%d", r.SurvivingLines),
+				fmt.Sprintf("%.1f", r.OwnershipPercent),
+				fmt.Sprintf("%d", r.LinesChanged),
+				fmt.Sprintf("%.1f", r.LinesChangedPercent),
+			})
+		}
+		return writeCSV(rows)
+	default:
+		displayOwnership(stats, ownership)
+		return nil
+	}
+}
+
+// displayOwnership displays current code ownership next to historical activity
+func displayOwnership(stats *RepositoryStats, ownership *OwnershipStats) {
+	// Overall ownership per author
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Author", "Surviving Lines", "Ownership %", "Lines Changed", "Lines Changed %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+
+	for _, r := range ownershipRecords(stats, ownership) {
+		table.Append([]string{
+			r.Author,
+			fmt.Sprintf("%d", r.SurvivingLines),
+			fmt.Sprintf("%.1f%%", r.OwnershipPercent),
+			fmt.Sprintf("%d", r.LinesChanged),
+			fmt.Sprintf("%.1f%%", r.LinesChangedPercent),
+		})
+	}
+	table.Append([]string{
+		"TOTAL",
+		fmt.Sprintf("%d", ownership.TotalLines),
+		"100%",
+		fmt.Sprintf("%d", stats.TotalLines),
+		"100%",
+	})
+	table.Render()
+
+	// Ownership per directory
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Directory", "Author", "Surviving Lines", "Ownership %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+
+	lastDir := ""
+	for _, r := range directoryOwnershipRecords(ownership) {
+		dirDisplay := ""
+		if r.Directory != lastDir {
+			// Only show the directory for its first author
+			dirDisplay = r.Directory
+			lastDir = r.Directory
+		}
+		table.Append([]string{
+			dirDisplay,
+			r.Author,
+			fmt.Sprintf("%d", r.SurvivingLines),
+			fmt.Sprintf("%.1f%%", r.OwnershipPercent),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeOwnership(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", start, map[string]string{
+		"main.go":     "package main\n\nfunc main() {\n}\n",
+		"lib/util.go": "package lib\n",
+		"go.sum":      "ignored\n",
+	})
+	r.commit("Bob", start.AddDate(0, 0, 1), map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+	})
+
+	stats := newTestStats()
+	stats.IgnoreFiles["go.sum"] = true
+
+	ownership, err := AnalyzeOwnership(r.repo, stats)
+	if err != nil {
+		t.Fatalf("Failed to analyze ownership: %v", err)
+	}
+
+	// Alice still owns 4 lines of main.go and all of lib/util.go; Bob added one line
+	if ownership.Authors["Alice"] != 5 {
+		t.Errorf("Expected Alice to own 5 lines, got %d", ownership.Authors["Alice"])
+	}
+	if ownership.Authors["Bob"] != 1 {
+		t.Errorf("Expected Bob to own 1 line, got %d", ownership.Authors["Bob"])
+	}
+	if ownership.TotalLines != 6 {
+		t.Errorf("Expected 6 surviving lines, got %d", ownership.TotalLines)
+	}
+	if len(ownership.Files) != 2 {
+		t.Errorf("Expected go.sum to be ignored, got %d files", len(ownership.Files))
+	}
+	if ownership.Directories["lib"]["Alice"] != 1 || ownership.Directories["."]["Bob"] != 1 {
+		t.Errorf("Unexpected directory ownership: %v", ownership.Directories)
+	}
+
+	if !ownership.LastChanged["Bob"].Equal(start.AddDate(0, 0, 1)) || !ownership.LastChanged["Alice"].Equal(start) {
+		t.Errorf("Expected the dates of the newest surviving lines, got %v", ownership.LastChanged)
+	}
+
+	// Directories include their subdirectories, up to the root
+	if ownership.Directories["."]["Alice"] != 5 {
+		t.Errorf("Expected the root to include lib/util.go, got %v", ownership.Directories["."])
+	}
+
+	// The report lists historical activity next to ownership
+	if err := analyzeRepository(r.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	records := ownershipRecords(stats, ownership)
+	if len(records) != 2 || records[0].Author != "Alice" || records[0].LinesChanged == 0 {
+		t.Errorf("Unexpected ownership records: %+v", records)
+	}
+}
+
+func TestAncestorDirectories(t *testing.T) {
+	tests := map[string][]string{
+		"main.go":         {"."},
+		"lib/util.go":     {"lib", "."},
+		"src/api/v1/h.go": {"src/api/v1", "src/api", "src", "."},
+	}
+	for filePath, expected := range tests {
+		dirs := ancestorDirectories(filePath)
+		if strings.Join(dirs, ",") != strings.Join(expected, ",") {
+			t.Errorf("%s: expected %v, got %v", filePath, expected, dirs)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Period is the size of the time buckets used for time series statistics
+type Period string
+
+// Supported periods
+const (
+	PeriodDay     Period = "day"
+	PeriodWeek    Period = "week"
+	PeriodMonth   Period = "month"
+	PeriodQuarter Period = "quarter"
+	PeriodYear    Period = "year"
+)
+
+// WeekStart is the first day of the week used for weekly buckets
+type WeekStart string
+
+// Supported week starts
+const (
+	WeekStartMonday WeekStart = "monday"
+	WeekStartSunday WeekStart = "sunday"
+)
+
+// parseWeekStart converts a command-line value into a WeekStart
+func parseWeekStart(value string) (WeekStart, error) {
+	switch weekStart := WeekStart(strings.ToLower(value)); weekStart {
+	case WeekStartMonday, WeekStartSunday:
+		return weekStart, nil
+	}
+	return "", fmt.Errorf("unsupported week start %q (use monday or sunday)", value)
+}
+
+// parsePeriod converts a command-line value into a Period
+func parsePeriod(value string) (Period, error) {
+	switch period := Period(value); period {
+	case PeriodDay, PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear:
+		return period, nil
+	}
+	return "", fmt.Errorf("unsupported period %q (use day, week, month, quarter or year)", value)
+}
+
+// Label returns the column heading used for the period in reports
+func (p Period) Label() string {
+	switch p {
+	case PeriodDay:
+		return "Day"
+	case PeriodMonth:
+		return "Month"
+	case PeriodQuarter:
+		return "Quarter"
+	case PeriodYear:
+		return "Year"
+	default:
+		return "Week"
+	}
+}
+
+// localTime converts a commit time into the time zone configured in stats.
+// Without a configured location the commit's own recorded offset is kept.
+func localTime(stats *RepositoryStats, t time.Time) time.Time {
+	if stats.Location == nil {
+		return t
+	}
+	return t.In(stats.Location)
+}
+
+// inDateRange reports whether a commit time falls within the Since/Until range in stats
+func inDateRange(stats *RepositoryStats, t time.Time) bool {
+	if !stats.Since.IsZero() && t.Before(stats.Since) {
+		return false
+	}
+	if !stats.Until.IsZero() && !t.Before(stats.Until) {
+		return false
+	}
+	return true
+}
+
+// parseDate parses a YYYY-MM-DD date at midnight in the given location
+func parseDate(value string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", value)
+	}
+	return date, nil
+}
+
+// periodBucket returns the bucket key and bucket start date for a point in time.
+// Weekly keys are ISO weeks; Sunday-based weeks take the key of the ISO week
+// starting the following Monday, so the displayed start always matches the key.
+func periodBucket(t time.Time, period Period, weekStart WeekStart) (string, time.Time) {
+	year, month, day := t.Date()
+	switch period {
+	case PeriodDay:
+		start := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		return start.Format("2006-01-02"), start
+	case PeriodMonth:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+		return start.Format("2006-01"), start
+	case PeriodQuarter:
+		quarter := (int(month)-1)/3 + 1
+		start := time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, t.Location())
+		return fmt.Sprintf("%d-Q%d", year, quarter), start
+	case PeriodYear:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+		return fmt.Sprintf("%d", year), start
+	default:
+		// Sunday-based weeks begin one day before the ISO week they belong to
+		shift := 0
+		if weekStart == WeekStartSunday {
+			shift = 1
+		}
+		date := time.Date(year, month, day+shift, 0, 0, 0, 0, t.Location())
+		isoYear, week := date.ISOWeek()
+		start := getWeekStart(isoYear, week, t.Location()).AddDate(0, 0, -shift)
+		return fmt.Sprintf("%d-W%02d", isoYear, week), start
+	}
+}
+
+// addToPeriod records a commit in the time series bucket for the given time
+func addToPeriod(series map[string]*PeriodStats, period Period, weekStart WeekStart, when time.Time, record *CommitRecord) {
+	key, start := periodBucket(when, period, weekStart)
+	authorName := record.Author
+	linesChanged := record.LinesChanged()
+
+	// Get or create the period stats
+	periodStats, ok := series[key]
+	if !ok {
+		periodStats = &PeriodStats{
+			Key:     key,
+			Start:   start,
+			Week:    start,
+			Authors: make(map[string]*PeriodAuthorStats),
+		}
+		series[key] = periodStats
+	}
+
+	// Get or create the author stats for this period
+	authorStats, ok := periodStats.Authors[authorName]
+	if !ok {
+		authorStats = &PeriodAuthorStats{
+			Name:  authorName,
+			Start: start,
+			Week:  start,
+		}
+		periodStats.Authors[authorName] = authorStats
+	}
+
+	// Update the period stats
+	authorStats.CommitCount++
+	authorStats.LinesChanged += linesChanged
+	authorStats.LinesAdded += record.LinesAdded()
+	authorStats.CodeLinesChanged += record.CodeLinesChanged()
+	periodStats.TotalCommits++
+	periodStats.TotalLines += linesChanged
+}
+
+// nextPeriodStart returns the start of the period following the one starting at start
+func nextPeriodStart(start time.Time, period Period) time.Time {
+	switch period {
+	case PeriodDay:
+		return start.AddDate(0, 0, 1)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	case PeriodQuarter:
+		return start.AddDate(0, 3, 0)
+	case PeriodYear:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 0, 7)
+	}
+}
+
+// fillPeriods adds empty periods to a time series so that it covers every
+// period from the one containing from to the one containing to. Zero from or
+// to values default to the first or last period already in the series.
+func fillPeriods(series map[string]*PeriodStats, period Period, weekStart WeekStart, from, to time.Time) {
+	// Default the range to the periods that already have commits
+	for _, periodStats := range series {
+		if from.IsZero() || periodStats.Start.Before(from) {
+			from = periodStats.Start
+		}
+		if to.IsZero() || periodStats.Start.After(to) {
+			to = periodStats.Start
+		}
+	}
+	if from.IsZero() || to.IsZero() {
+		return
+	}
+
+	// Walk the range one period at a time, adding the periods that are missing
+	_, start := periodBucket(from, period, weekStart)
+	for !start.After(to) {
+		key, bucketStart := periodBucket(start, period, weekStart)
+		if _, ok := series[key]; !ok {
+			series[key] = &PeriodStats{
+				Key:     key,
+				Start:   bucketStart,
+				Week:    bucketStart,
+				Authors: make(map[string]*PeriodAuthorStats),
+			}
+		}
+		start = nextPeriodStart(bucketStart, period)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodBucket(t *testing.T) {
+	when := time.Date(2025, 5, 14, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		period    Period
+		wantKey   string
+		wantStart string
+	}{
+		{PeriodDay, "2025-05-14", "2025-05-14"},
+		{PeriodMonth, "2025-05", "2025-05-01"},
+		{PeriodQuarter, "2025-Q2", "2025-04-01"},
+		{PeriodYear, "2025", "2025-01-01"},
+	}
+
+	for _, tt := range tests {
+		key, start := periodBucket(when, tt.period, WeekStartMonday)
+		if key != tt.wantKey {
+			t.Errorf("periodBucket(%s) key = %s, want %s", tt.period, key, tt.wantKey)
+		}
+		if got := start.Format("2006-01-02"); got != tt.wantStart {
+			t.Errorf("periodBucket(%s) start = %s, want %s", tt.period, got, tt.wantStart)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	if period, err := parsePeriod("quarter"); err != nil || period != PeriodQuarter {
+		t.Errorf("parsePeriod(quarter) = %s, %v", period, err)
+	}
+	if _, err := parsePeriod("fortnight"); err == nil {
+		t.Errorf("Expected an error for an unsupported period")
+	}
+}
+
+func TestAggregateCommitMonthlyPeriods(t *testing.T) {
+	stats := newTestStats()
+	stats.Period = PeriodMonth
+
+	commits := []*CommitRecord{
+		{Author: "Alice", When: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), Files: []FileChange{{Name: "a.go", Additions: 3}}},
+		{Author: "Alice", When: time.Date(2025, 1, 28, 0, 0, 0, 0, time.UTC), Files: []FileChange{{Name: "a.go", Deletions: 2}}},
+		{Author: "Bob", When: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC), Files: []FileChange{{Name: "b.go", Additions: 7}}},
+	}
+	for _, commit := range commits {
+		aggregateCommit(stats, commit)
+	}
+
+	if len(stats.Periods) != 2 {
+		t.Fatalf("Expected 2 monthly periods, got %d", len(stats.Periods))
+	}
+	january := stats.Periods["2025-01"]
+	if january == nil || january.TotalCommits != 2 || january.Authors["Alice"].LinesChanged != 5 {
+		t.Errorf("Unexpected January stats: %+v", january)
+	}
+
+	// The weekly series is still maintained alongside the requested period;
+	// Sunday 2 February belongs to the same ISO week as 28 January
+	if len(stats.WeeklyStats) != 2 {
+		t.Errorf("Expected 2 weekly buckets, got %d", len(stats.WeeklyStats))
+	}
+
+	// Callers of the weekly types still find the start of the week in Week
+	for _, week := range stats.WeeklyStats {
+		if !week.Week.Equal(week.Start) {
+			t.Errorf("Expected Week to match Start %v, got %v", week.Start, week.Week)
+		}
+		for _, author := range week.Authors {
+			if !author.Week.Equal(week.Start) {
+				t.Errorf("Expected %s's Week to match Start %v, got %v", author.Name, week.Start, author.Week)
+			}
+		}
+	}
+}
+
+func TestGetWeekStart(t *testing.T) {
+	tests := []struct {
+		year, week int
+		want       string
+	}{
+		{2025, 14, "2025-03-31"},
+		{2023, 1, "2023-01-02"}, // January 1st is a Sunday
+		{2017, 1, "2017-01-02"}, // January 1st is a Sunday
+		{2021, 1, "2021-01-04"}, // January 1st is a Friday
+		{2020, 1, "2019-12-30"}, // Week 1 starts in the previous year
+		{2020, 53, "2020-12-28"},
+	}
+
+	for _, tt := range tests {
+		got := getWeekStart(tt.year, tt.week, time.UTC)
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("getWeekStart(%d, %d) = %s, want %s", tt.year, tt.week, got.Format("2006-01-02"), tt.want)
+		}
+		if got.Weekday() != time.Monday {
+			t.Errorf("getWeekStart(%d, %d) is a %s, want Monday", tt.year, tt.week, got.Weekday())
+		}
+	}
+}
+
+func TestWeekBucketMatchesKey(t *testing.T) {
+	// Sunday 1 January 2023 belongs to ISO week 2022-W52
+	sunday := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	key, start := periodBucket(sunday, PeriodWeek, WeekStartMonday)
+	if key != "2022-W52" || start.Format("2006-01-02") != "2022-12-26" {
+		t.Errorf("Monday weeks: got %s starting %s", key, start.Format("2006-01-02"))
+	}
+
+	// With Sunday weeks it starts the week leading into ISO week 2023-W01
+	key, start = periodBucket(sunday, PeriodWeek, WeekStartSunday)
+	if key != "2023-W01" || start.Format("2006-01-02") != "2023-01-01" {
+		t.Errorf("Sunday weeks: got %s starting %s", key, start.Format("2006-01-02"))
+	}
+
+	// Every day of a Sunday week shares its bucket
+	saturday := time.Date(2023, 1, 7, 23, 0, 0, 0, time.UTC)
+	if key, _ := periodBucket(saturday, PeriodWeek, WeekStartSunday); key != "2023-W01" {
+		t.Errorf("Expected Saturday to stay in 2023-W01, got %s", key)
+	}
+}
+
+func TestAggregateCommitUsesLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	stats := newTestStats()
+	stats.Location = tokyo
+
+	// Sunday evening in UTC is already Monday morning in Tokyo
+	when := time.Date(2025, 4, 6, 20, 0, 0, 0, time.UTC)
+	aggregateCommit(stats, &CommitRecord{Author: "Alice", When: when, Files: []FileChange{{Name: "a.go", Additions: 1}}})
+
+	if _, ok := stats.WeeklyStats["2025-W15"]; !ok {
+		t.Errorf("Expected the commit in 2025-W15, got %v", stats.WeeklyStats)
+	}
+}
+
+func TestParseWeekStart(t *testing.T) {
+	if weekStart, err := parseWeekStart("Sunday"); err != nil || weekStart != WeekStartSunday {
+		t.Errorf("parseWeekStart(Sunday) = %s, %v", weekStart, err)
+	}
+	if _, err := parseWeekStart("friday"); err == nil {
+		t.Errorf("Expected an error for an unsupported week start")
+	}
+}
+
+func TestFillPeriods(t *testing.T) {
+	stats := newTestStats()
+	stats.Period = PeriodMonth
+	aggregateCommit(stats, &CommitRecord{Author: "Alice", When: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Files: []FileChange{{Name: "a.go", Additions: 1}}})
+	aggregateCommit(stats, &CommitRecord{Author: "Bob", When: time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC), Files: []FileChange{{Name: "b.go", Additions: 1}}})
+
+	// Without a range the gap between the first and last commit is filled
+	fillPeriods(stats.Periods, PeriodMonth, WeekStartMonday, time.Time{}, time.Time{})
+	for _, key := range []string{"2025-01", "2025-02", "2025-03", "2025-04"} {
+		if _, ok := stats.Periods[key]; !ok {
+			t.Errorf("Expected period %s to be present", key)
+		}
+	}
+	if len(stats.Periods["2025-02"].Authors) != 0 {
+		t.Errorf("Expected filled period to be empty")
+	}
+
+	// An explicit range extends the series beyond the commits
+	fillPeriods(stats.Periods, PeriodMonth, WeekStartMonday,
+		time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC))
+	if len(stats.Periods) != 8 {
+		t.Errorf("Expected 8 months from 2024-11 to 2025-06, got %d", len(stats.Periods))
+	}
+
+	records := periodRecords(stats, stats.Periods)
+	if len(records) != 8 || records[0].Period != "2024-11" || records[0].Author != "" {
+		t.Errorf("Expected empty periods in the records, got %+v", records)
+	}
+}
+
+func TestWalkCommitsDateRange(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("Alice", time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC), map[string]string{"a.txt": "1\n"})
+	r.commit("Bob", time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC), map[string]string{"a.txt": "1\n2\n"})
+	r.commit("Carol", time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC), map[string]string{"a.txt": "1\n2\n3\n"})
+
+	stats := newTestStats()
+	stats.Since = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	stats.Until = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := analyzeRepository(r.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+
+	if stats.TotalCommits != 1 || stats.Authors["Bob"] == nil {
+		t.Errorf("Expected only Bob's commit in range, got %d commits", stats.TotalCommits)
+	}
+}
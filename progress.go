@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Progress describes how far a running analysis has come
+type Progress struct {
+	Commits int       // Commits processed so far
+	Date    time.Time // Author date of the commit currently being processed
+	Rate    float64   // Commits processed per second since the analysis started
+}
+
+// ProgressFunc is called by the analysis engine after each processed commit
+type ProgressFunc func(Progress)
+
+// progressBar renders analysis progress as a single self-overwriting line
+type progressBar struct {
+	out        io.Writer
+	width      int
+	lastRender time.Time
+	rendered   bool
+	frame      int // Redraws so far, to move the marker
+}
+
+// progressMarker is drawn moving across the bar, as the number of commits is
+// not known up front
+const progressMarker = "<=>"
+
+// progressRenderInterval limits how often the progress bar is redrawn
+const progressRenderInterval = 100 * time.Millisecond
+
+// newProgressBar creates a progress bar writing to the given writer
+func newProgressBar(out io.Writer) *progressBar {
+	return &progressBar{
+		out:   out,
+		width: 30,
+	}
+}
+
+// Update redraws the progress bar, throttled to progressRenderInterval
+func (b *progressBar) Update(p Progress) {
+	now := time.Now()
+	if b.rendered && now.Sub(b.lastRender) < progressRenderInterval {
+		return
+	}
+	b.lastRender = now
+	b.rendered = true
+
+	date := ""
+	if !p.Date.IsZero() {
+		date = p.Date.Format("2006-01-02")
+	}
+
+	fmt.Fprintf(b.out, "\r[%s] %d commits  %s  %.1f commits/s", b.marker(), p.Commits, date, p.Rate)
+}
+
+// marker returns the bar with a marker that moves back and forth on every
+// redraw
+func (b *progressBar) marker() string {
+	span := b.width - len(progressMarker)
+	position := b.frame % (2 * span)
+	if position > span {
+		position = 2*span - position
+	}
+	b.frame++
+	return strings.Repeat(" ", position) + progressMarker + strings.Repeat(" ", span-position)
+}
+
+// Finish clears the progress bar line so regular output starts on a clean line
+func (b *progressBar) Finish() {
+	if !b.rendered {
+		return
+	}
+	fmt.Fprintf(b.out, "\r%s\r", strings.Repeat(" ", b.width+70))
+}
+
+// isTerminal reports whether the given file is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// shouldShowProgress decides whether the CLI should draw a progress bar.
+// Progress is only shown on an interactive stderr, and never when stdout is
+// redirected or a machine-readable output format has been selected.
+func shouldShowProgress(format string) bool {
+	if isMachineFormat(format) {
+		return false
+	}
+	return isTerminal(os.Stderr) && isTerminal(os.Stdout)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeRepositoryReportsProgress(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", start, map[string]string{"a.txt": "one\n"})
+	r.commit("Bob", start.AddDate(0, 0, 1), map[string]string{"a.txt": "one\ntwo\n"})
+	r.commit("Alice", start.AddDate(0, 0, 2), map[string]string{"b.txt": "three\n"})
+
+	// Collect every progress update
+	var updates []Progress
+	stats := newTestStats()
+	stats.OnProgress = func(p Progress) {
+		updates = append(updates, p)
+	}
+
+	if err := analyzeRepository(r.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+
+	if len(updates) != 3 {
+		t.Fatalf("Expected 3 progress updates, got %d", len(updates))
+	}
+	for i, p := range updates {
+		if p.Commits != i+1 {
+			t.Errorf("Update %d: expected %d commits processed, got %d", i, i+1, p.Commits)
+		}
+		if p.Date.IsZero() {
+			t.Errorf("Update %d: expected a commit date", i)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := newProgressBar(&buf)
+
+	bar.Update(Progress{Commits: 5, Date: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), Rate: 2.5})
+	output := buf.String()
+	for _, want := range []string{"[<=>", "5 commits", "2025-04-01", "2.5 commits/s"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected progress output to contain %q, got %q", want, output)
+		}
+	}
+
+	// Updates arriving faster than the render interval are dropped
+	bar.Update(Progress{Commits: 6})
+	if strings.Contains(buf.String(), "6 commits") {
+		t.Errorf("Expected throttled update to be skipped")
+	}
+
+	// The marker moves on every redraw
+	bar.lastRender = time.Time{}
+	bar.Update(Progress{Commits: 7})
+	if output := buf.String(); !strings.Contains(output, "[ <=>") || !strings.Contains(output, "7 commits") {
+		t.Errorf("Expected the marker to move, got %q", output)
+	}
+
+	if isMachineFormat(FormatTable) || !isMachineFormat(FormatJSON) {
+		t.Errorf("Unexpected machine format classification")
+	}
+}
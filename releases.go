@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/olekukonko/tablewriter"
+)
+
+// TagOrder determines the order of releases
+type TagOrder string
+
+// Supported tag orders
+const (
+	TagOrderSemver TagOrder = "semver" // By version number, leaving out tags that are not versions
+	TagOrderDate   TagOrder = "date"   // By the date of the tagged commit
+)
+
+// UnreleasedLabel names the commits after the last release
+const UnreleasedLabel = "Unreleased"
+
+// ReleaseTopFiles is the number of files listed per release
+const ReleaseTopFiles = 5
+
+// parseTagOrder validates a tag order
+func parseTagOrder(value string) (TagOrder, error) {
+	switch TagOrder(value) {
+	case TagOrderSemver, TagOrderDate:
+		return TagOrder(value), nil
+	}
+	return "", fmt.Errorf("unsupported tag order %q (expected semver or date)", value)
+}
+
+// releaseTag is a tag and the commit it points to
+type releaseTag struct {
+	Name    string
+	Commit  plumbing.Hash
+	Date    time.Time // Committer date of the tagged commit
+	version []string  // Dot-separated version numbers, for semver order
+	pre     []string  // Dot-separated pre-release identifiers, for semver order
+}
+
+// Release holds the statistics of the commits between two consecutive tags
+type Release struct {
+	Tag      string    // Tag name, or UnreleasedLabel for the commits after the last tag
+	Previous string    // The previous tag, empty for the first release
+	Date     time.Time // Date of the tagged commit, zero when unreleased
+	Stats    *RepositoryStats
+}
+
+// listReleaseTags returns the tags of a repository in release order, oldest first
+func listReleaseTags(repo *git.Repository, order TagOrder) ([]*releaseTag, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	var tags []*releaseTag
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		tag := &releaseTag{Name: ref.Name().Short()}
+		if order == TagOrderSemver {
+			var ok bool
+			if tag.version, tag.pre, ok = parseVersion(tag.Name); !ok {
+				return nil
+			}
+		}
+
+		// Peel annotated tags to the commit they point to
+		hash, err := repo.ResolveRevision(plumbing.Revision(ref.Name().String()))
+		if err != nil {
+			return nil
+		}
+		commit, err := repo.CommitObject(*hash)
+		if err != nil {
+			return nil
+		}
+		tag.Commit, tag.Date = commit.Hash, commit.Committer.When
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if order == TagOrderSemver {
+			if c := compareVersions(tags[i], tags[j]); c != 0 {
+				return c < 0
+			}
+		} else if !tags[i].Date.Equal(tags[j].Date) {
+			return tags[i].Date.Before(tags[j].Date)
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// parseVersion splits a version tag such as v1.2.3 or 2.0-rc.1 into its
+// numbers and pre-release identifiers; build metadata is ignored
+func parseVersion(name string) (version, pre []string, ok bool) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "v"), "V")
+	name, _, _ = strings.Cut(name, "+")
+	name, preRelease, hasPre := strings.Cut(name, "-")
+	version = strings.Split(name, ".")
+	if len(version) > 3 {
+		return nil, nil, false
+	}
+	for _, part := range version {
+		if _, err := strconv.Atoi(part); err != nil {
+			return nil, nil, false
+		}
+	}
+	if hasPre {
+		pre = strings.Split(preRelease, ".")
+	}
+	return version, pre, true
+}
+
+// compareVersions compares the versions of two tags by semver precedence,
+// returning a negative number, zero or a positive number. Missing version
+// numbers count as zero and a pre-release precedes its release.
+func compareVersions(a, b *releaseTag) int {
+	for i := 0; i < 3; i++ {
+		if c := compareNumbers(versionPart(a.version, i), versionPart(b.version, i)); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		if c := compareIdentifiers(a.pre[i], b.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a.pre) - len(b.pre)
+}
+
+// versionPart returns the i-th version number, or "0" when missing
+func versionPart(version []string, i int) string {
+	if i < len(version) {
+		return version[i]
+	}
+	return "0"
+}
+
+// compareNumbers compares two decimal numbers
+func compareNumbers(a, b string) int {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x - y
+}
+
+// compareIdentifiers compares pre-release identifiers: numeric identifiers
+// numerically and before alphanumeric ones, which compare as text
+func compareIdentifiers(a, b string) int {
+	_, errA := strconv.Atoi(a)
+	_, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareNumbers(a, b)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// AnalyzeReleases buckets the commits of a repository between consecutive
+// tags and analyzes each release with the given settings. Each commit belongs
+// to the earliest release whose tag has it in its history; commits in the
+// history of HEAD but of no tag are reported as unreleased. Tags on the same
+// commit are merged into one release. History is walked once. Releases are
+// returned newest first.
+func AnalyzeReleases(repo *git.Repository, settings *RepositoryStats, order TagOrder) ([]*Release, error) {
+	tags, err := listReleaseTags(repo, order)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		if order == TagOrderSemver {
+			return nil, fmt.Errorf("no version tags found (use -tag-order=date to use every tag)")
+		}
+		return nil, fmt.Errorf("no tags found")
+	}
+
+	// One release per tagged commit, named after all of its tags
+	var releases []*Release
+	var tips []plumbing.Hash
+	index := make(map[plumbing.Hash]int)
+	for _, tag := range tags {
+		if i, ok := index[tag.Commit]; ok {
+			releases[i].Tag += ", " + tag.Name
+			continue
+		}
+		index[tag.Commit] = len(releases)
+		releases = append(releases, &Release{Tag: tag.Name, Date: tag.Date, Stats: newRepositoryStats(settings)})
+		tips = append(tips, tag.Commit)
+	}
+
+	// Commits made since the last release
+	head, err := resolveRevision(repo, "")
+	if err != nil {
+		return nil, err
+	}
+	unreleased := &Release{Tag: UnreleasedLabel, Stats: newRepositoryStats(settings)}
+	releases = append(releases, unreleased)
+	tips = append(tips, head)
+
+	// Link each release to the previous one once all tags have been merged
+	for i := 1; i < len(releases); i++ {
+		releases[i].Previous = releases[i-1].Tag
+	}
+
+	commits, buckets, err := bucketCommits(repo, tips)
+	if err != nil {
+		return nil, err
+	}
+	options := diffOptionsFor(settings)
+	for _, c := range commits {
+		release := releases[buckets[c.Hash]]
+		record, err := commitRecord(repo, release.Stats, c, options)
+		if err != nil {
+			return nil, fmt.Errorf("analyzing %s: %w", release.Tag, err)
+		}
+		if record != nil {
+			aggregateCommit(release.Stats, record)
+		}
+	}
+	if unreleased.Stats.TotalCommits == 0 {
+		releases = releases[:len(releases)-1]
+	}
+
+	// Newest first
+	for i, j := 0, len(releases)-1; i < j; i, j = i+1, j-1 {
+		releases[i], releases[j] = releases[j], releases[i]
+	}
+	return releases, nil
+}
+
+// bucketCommits walks the history of the given tips once and assigns every
+// commit to the first tip, by position, that has it in its history. Commits
+// are returned newest first.
+func bucketCommits(repo *git.Repository, tips []plumbing.Hash) ([]*object.Commit, map[plumbing.Hash]int, error) {
+	// Collect the commits and count the children of each one
+	var commits []*object.Commit
+	children := make(map[plumbing.Hash]int)
+	seen := make(map[plumbing.Hash]bool)
+	pending := append([]plumbing.Hash(nil), tips...)
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		commits = append(commits, c)
+		for _, parent := range c.ParentHashes {
+			children[parent]++
+			pending = append(pending, parent)
+		}
+	}
+
+	// Each tip claims itself, and a commit inherits the earliest bucket of its
+	// children once all of them have been visited
+	buckets := make(map[plumbing.Hash]int)
+	for i := len(tips) - 1; i >= 0; i-- {
+		buckets[tips[i]] = i
+	}
+	byHash := make(map[plumbing.Hash]*object.Commit, len(commits))
+	var ready []*object.Commit
+	for _, c := range commits {
+		byHash[c.Hash] = c
+		if children[c.Hash] == 0 {
+			ready = append(ready, c)
+		}
+	}
+	for len(ready) > 0 {
+		c := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		for _, parent := range c.ParentHashes {
+			if bucket, ok := buckets[parent]; !ok || buckets[c.Hash] < bucket {
+				buckets[parent] = buckets[c.Hash]
+			}
+			if children[parent]--; children[parent] == 0 {
+				ready = append(ready, byHash[parent])
+			}
+		}
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
+	return commits, buckets, nil
+}
+
+// releaseContributor is a contributor to a release
+type releaseContributor struct {
+	Name         string `json:"name"`
+	Commits      int    `json:"commits"`
+	LinesChanged int    `json:"lines_changed"`
+}
+
+// releaseContributors returns the contributors of a release sorted by commits
+func releaseContributors(release *Release) []releaseContributor {
+	contributors := []releaseContributor{}
+	for _, author := range sortedAuthors(release.Stats) {
+		contributors = append(contributors, releaseContributor{author.Name, author.CommitCount, author.LinesChanged})
+	}
+	return contributors
+}
+
+// releaseDate formats the date of a release, empty when unreleased
+func releaseDate(release *Release) string {
+	if release.Date.IsZero() {
+		return ""
+	}
+	return release.Date.Format("2006-01-02")
+}
+
+// writeReleases writes the per-release statistics in the requested format
+func writeReleases(releases []*Release, format string) error {
+	switch format {
+	case FormatJSON:
+		type releaseRecord struct {
+			Tag          string               `json:"tag"`
+			Previous     string               `json:"previous,omitempty"`
+			Date         string               `json:"date,omitempty"`
+			Commits      int                  `json:"commits"`
+			LinesChanged int                  `json:"lines_changed"`
+			Contributors []releaseContributor `json:"contributors"`
+			TopFiles     []fileRecord         `json:"top_files"`
+		}
+		records := []releaseRecord{}
+		for _, r := range releases {
+			records = append(records, releaseRecord{
+				Tag:          r.Tag,
+				Previous:     r.Previous,
+				Date:         releaseDate(r),
+				Commits:      r.Stats.TotalCommits,
+				LinesChanged: r.Stats.TotalLines,
+				Contributors: releaseContributors(r),
+				TopFiles:     fileRecords(r.Stats, ReleaseTopFiles),
+			})
+		}
+		return writeJSON(struct {
+			Releases []releaseRecord `json:"releases"`
+		}{records})
+	case FormatCSV:
+		rows := [][]string{{"scope", "release", "previous", "date", "name", "commits", "lines_changed"}}
+		for _, r := range releases {
+			row := func(scope, name string, commits, linesChanged int) []string {
+				return []string{scope, r.Tag, r.Previous, releaseDate(r), name, fmt.Sprintf("%d", commits), fmt.Sprintf("%d", linesChanged)}
+			}
+			rows = append(rows, row("release", "", r.Stats.TotalCommits, r.Stats.TotalLines))
+			for _, c := range releaseContributors(r) {
+				rows = append(rows, row("contributor", c.Name, c.Commits, c.LinesChanged))
+			}
+			for _, f := range fileRecords(r.Stats, ReleaseTopFiles) {
+				rows = append(rows, row("file", f.Path, f.Commits, f.LinesChanged))
+			}
+		}
+		return writeCSV(rows)
+	default:
+		displayReleases(releases)
+		return nil
+	}
+}
+
+// displayReleases displays the per-release statistics in an ASCII table
+func displayReleases(releases []*Release) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Release", "Date", "Commits", "Lines Changed", "Contributors", "Top Files"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	for _, r := range releases {
+		var contributors, files []string
+		for _, c := range releaseContributors(r) {
+			contributors = append(contributors, fmt.Sprintf("%s (%d)", c.Name, c.Commits))
+		}
+		for _, f := range fileRecords(r.Stats, ReleaseTopFiles) {
+			files = append(files, fmt.Sprintf("%s (%d)", f.Path, f.LinesChanged))
+		}
+		table.Append([]string{
+			r.Tag,
+			releaseDate(r),
+			fmt.Sprintf("%d", r.Stats.TotalCommits),
+			fmt.Sprintf("%d", r.Stats.TotalLines),
+			strings.Join(contributors, "\n"),
+			strings.Join(files, "\n"),
+		})
+	}
+	table.Render()
+}
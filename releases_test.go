@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCompareVersions(t *testing.T) {
+	ordered := []string{"v0.9", "v0.10.0-alpha", "v0.10.0-alpha.2", "v0.10.0-alpha.10", "v0.10.0-beta", "v0.10.0", "1.0.0+build.5", "v1.0.1"}
+	for i := 1; i < len(ordered); i++ {
+		a, b := &releaseTag{Name: ordered[i-1]}, &releaseTag{Name: ordered[i]}
+		var ok bool
+		if a.version, a.pre, ok = parseVersion(a.Name); !ok {
+			t.Fatalf("Expected %s to be a version", a.Name)
+		}
+		if b.version, b.pre, ok = parseVersion(b.Name); !ok {
+			t.Fatalf("Expected %s to be a version", b.Name)
+		}
+		if compareVersions(a, b) >= 0 || compareVersions(b, a) <= 0 {
+			t.Errorf("Expected %s to precede %s", a.Name, b.Name)
+		}
+	}
+
+	for _, name := range []string{"latest", "release-2024", "v1.2.3.4", "v"} {
+		if _, _, ok := parseVersion(name); ok {
+			t.Errorf("Expected %s not to be a version", name)
+		}
+	}
+}
+
+func TestAnalyzeReleases(t *testing.T) {
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo(t)
+	first := r.commit("alice", when, map[string]string{"a.txt": "1\n"})
+	r.commit("bob", when.AddDate(0, 0, 1), map[string]string{"b.txt": "1\n2\n"})
+	second := r.commit("alice", when.AddDate(0, 0, 2), map[string]string{"a.txt": "1\n2\n3\n"})
+	third := r.commit("carol", when.AddDate(0, 0, 3), map[string]string{"c.txt": "1\n"})
+	r.commit("dave", when.AddDate(0, 0, 4), map[string]string{"d.txt": "1\n"})
+
+	// v1.10.0 is tagged before v1.9.0 and annotated
+	signature := &object.Signature{Name: "alice", Email: "alice@example.com", When: when}
+	for name, hash := range map[string]string{"v1.2.0": first, "v1.9.0": third, "nightly": third} {
+		if _, err := r.repo.CreateTag(name, plumbing.NewHash(hash), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.repo.CreateTag("v1.10.0", plumbing.NewHash(second), &git.CreateTagOptions{Tagger: signature, Message: "Release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	releases, err := AnalyzeReleases(r.repo, newTestStats(), TagOrderSemver)
+	if err != nil {
+		t.Fatalf("Failed to analyze releases: %v", err)
+	}
+	var tags []string
+	for _, release := range releases {
+		tags = append(tags, release.Tag)
+	}
+	if len(releases) != 4 || tags[0] != UnreleasedLabel || tags[1] != "v1.10.0" || tags[2] != "v1.9.0" || tags[3] != "v1.2.0" {
+		t.Fatalf("Expected unreleased work and three versions newest first, got %v", tags)
+	}
+
+	// Every commit of v1.10.0 is already in v1.9.0, the earlier release
+	if releases[1].Previous != "v1.9.0" || releases[1].Stats.TotalCommits != 0 {
+		t.Errorf("Expected v1.10.0 to follow v1.9.0 without commits of its own, got %d", releases[1].Stats.TotalCommits)
+	}
+	if v19 := releases[2].Stats; v19.TotalCommits != 3 || len(v19.Authors) != 3 {
+		t.Errorf("Expected 3 commits by 3 authors in v1.9.0, got %d by %d", v19.TotalCommits, len(v19.Authors))
+	}
+	if unreleased := releases[0].Stats; unreleased.TotalCommits != 1 || unreleased.Authors["dave"] == nil {
+		t.Errorf("Expected the commit outside the history of every tag to be unreleased, got %d", unreleased.TotalCommits)
+	}
+
+	// By date, every tag counts, tags on the same commit make one release and
+	// the annotated tag is dated by its commit
+	if releases, err = AnalyzeReleases(r.repo, newTestStats(), TagOrderDate); err != nil {
+		t.Fatalf("Failed to analyze releases: %v", err)
+	}
+	tags = nil
+	for _, release := range releases {
+		tags = append(tags, release.Tag)
+	}
+	expected := []string{UnreleasedLabel, "nightly, v1.9.0", "v1.10.0", "v1.2.0"}
+	if len(tags) != len(expected) {
+		t.Fatalf("Expected releases %v, got %v", expected, tags)
+	}
+	for i := range expected {
+		if tags[i] != expected[i] {
+			t.Fatalf("Expected releases %v, got %v", expected, tags)
+		}
+	}
+	if releases[2].Stats.TotalCommits != 2 || releases[1].Stats.TotalCommits != 1 {
+		t.Errorf("Expected 2 commits in v1.10.0 and 1 in v1.9.0 by date, got %d and %d",
+			releases[2].Stats.TotalCommits, releases[1].Stats.TotalCommits)
+	}
+	if releases[1].Previous != "v1.10.0" || releases[0].Previous != "nightly, v1.9.0" {
+		t.Errorf("Expected merged tags to be named together, got %q and %q", releases[1].Previous, releases[0].Previous)
+	}
+}
+
+func TestBucketCommitsAcrossMerges(t *testing.T) {
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo(t)
+	base := plumbing.NewHash(r.commit("alice", when, map[string]string{"a.txt": "1\n"}))
+	trunk := plumbing.NewHash(r.commit("bob", when.AddDate(0, 0, 1), map[string]string{"b.txt": "1\n"}))
+
+	// A release branch off base, tagged and then merged back
+	commit := func(name string, day int, parents ...plumbing.Hash) plumbing.Hash {
+		signature := &object.Signature{Name: name, Email: name + "@example.com", When: when.AddDate(0, 0, day)}
+		hash, err := r.wt.Commit("Commit by "+name, &git.CommitOptions{Author: signature, Committer: signature, Parents: parents})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	branch := commit("carol", 2, base)
+	merge := commit("dave", 3, trunk, branch)
+
+	commits, buckets, err := bucketCommits(r.repo, []plumbing.Hash{branch, merge})
+	if err != nil {
+		t.Fatalf("Failed to bucket commits: %v", err)
+	}
+	if len(commits) != 4 || commits[0].Hash != merge {
+		t.Fatalf("Expected 4 commits newest first, got %d", len(commits))
+	}
+	expected := map[plumbing.Hash]int{base: 0, branch: 0, trunk: 1, merge: 1}
+	for hash, bucket := range expected {
+		if buckets[hash] != bucket {
+			t.Errorf("Expected %s in bucket %d, got %d", hash, bucket, buckets[hash])
+		}
+	}
+}
+
+func TestAnalyzeReleasesMergesLaterTags(t *testing.T) {
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo(t)
+	first := r.commit("alice", when, map[string]string{"a.txt": "1\n"})
+	second := r.commit("bob", when.AddDate(0, 0, 1), map[string]string{"b.txt": "1\n2\n"})
+
+	// v1.2.0 is a later version on the commit of v1.0.0
+	for name, hash := range map[string]string{"v1.0.0": first, "v1.1.0": second, "v1.2.0": first} {
+		if _, err := r.repo.CreateTag(name, plumbing.NewHash(hash), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	releases, err := AnalyzeReleases(r.repo, newTestStats(), TagOrderSemver)
+	if err != nil {
+		t.Fatalf("Failed to analyze releases: %v", err)
+	}
+	if len(releases) != 2 || releases[0].Tag != "v1.1.0" || releases[1].Tag != "v1.0.0, v1.2.0" {
+		t.Fatalf("Expected v1.1.0 and the merged v1.0.0 release, got %d releases", len(releases))
+	}
+	if releases[0].Previous != "v1.0.0, v1.2.0" {
+		t.Errorf("Expected v1.1.0 to follow the merged release, got %q", releases[0].Previous)
+	}
+
+	// Every release has a totals row, even without contributors
+	releases[0].Stats = newTestStats()
+	output := captureStdout(t, func() error { return writeReleases(releases, FormatCSV) })
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV output: %v", err)
+	}
+	expected := [][]string{
+		{"scope", "release", "previous", "date", "name", "commits", "lines_changed"},
+		{"release", "v1.1.0", "v1.0.0, v1.2.0", "2024-03-05", "", "0", "0"},
+		{"release", "v1.0.0, v1.2.0", "", "2024-03-04", "", "1", "1"},
+		{"contributor", "v1.0.0, v1.2.0", "", "2024-03-04", "alice", "1", "1"},
+		{"file", "v1.0.0, v1.2.0", "", "2024-03-04", "a.txt", "1", "1"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected CSV rows %v, got %v", expected, rows)
+	}
+}
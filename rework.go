@@ -0,0 +1,128 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// DefaultReworkWindow is how soon an added line must be changed again to count as rework
+const DefaultReworkWindow = 21 * 24 * time.Hour
+
+// lineOrigin records who added a line and when
+type lineOrigin struct {
+	author string
+	when   time.Time
+}
+
+// ReworkTracker finds added lines that are modified or deleted again within
+// stats.ReworkWindow. Records must carry line-level edits, which WalkCommits
+// keeps whenever stats.ReworkWindow is set.
+type ReworkTracker struct {
+	stats   *RepositoryStats
+	records []*CommitRecord
+}
+
+// NewReworkTracker creates a rework tracker writing its results into stats
+func NewReworkTracker(stats *RepositoryStats) *ReworkTracker {
+	return &ReworkTracker{stats: stats}
+}
+
+// Add queues a commit record for rework tracking. It can be passed directly to WalkCommits.
+func (t *ReworkTracker) Add(record *CommitRecord) error {
+	t.records = append(t.records, record)
+	return nil
+}
+
+// Finish replays the queued commits from oldest to newest and credits the
+// reworked lines to the authors, weeks and periods in which they were written
+func (t *ReworkTracker) Finish() {
+	records := make([]*CommitRecord, len(t.records))
+	copy(records, t.records)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].When.Before(records[j].When)
+	})
+
+	// Follow the origin of every line of every file through the history
+	files := make(map[string][]lineOrigin)
+	for _, record := range records {
+		for _, change := range record.Files {
+			files[change.Name] = t.apply(files[change.Name], record, change.ops)
+		}
+	}
+	t.records = nil
+}
+
+// apply replays the line-level edits of a file change on the line origins of
+// the file, counting deleted lines that were added within the rework window
+func (t *ReworkTracker) apply(old []lineOrigin, record *CommitRecord, ops []lineOp) []lineOrigin {
+	updated := make([]lineOrigin, 0, len(old))
+	index := 0
+
+	// take returns the origins of the next n old lines. Lines beyond the known
+	// history, e.g. after a merge from another branch, have an unknown origin.
+	take := func(n int) []lineOrigin {
+		taken := make([]lineOrigin, n)
+		for i := 0; i < n && index < len(old); i++ {
+			taken[i] = old[index]
+			index++
+		}
+		return taken
+	}
+
+	for _, op := range ops {
+		switch op.Type {
+		case fdiff.Equal:
+			updated = append(updated, take(op.Lines)...)
+		case fdiff.Delete:
+			for _, origin := range take(op.Lines) {
+				t.countRework(origin, record.When)
+			}
+		case fdiff.Add:
+			for i := 0; i < op.Lines; i++ {
+				updated = append(updated, lineOrigin{author: record.Author, when: record.When})
+			}
+		}
+	}
+	return updated
+}
+
+// countRework credits a removed line to its author if it was removed within the window
+func (t *ReworkTracker) countRework(origin lineOrigin, removed time.Time) {
+	if origin.author == "" {
+		return
+	}
+	age := removed.Sub(origin.when)
+	if age < 0 || age > t.stats.ReworkWindow {
+		return
+	}
+
+	if author, ok := t.stats.Authors[origin.author]; ok {
+		author.ReworkedLines++
+	}
+
+	// Credit the week and period in which the line was written
+	when := localTime(t.stats, origin.when)
+	weekKey, _ := periodBucket(when, PeriodWeek, t.stats.WeekStart)
+	creditPeriodRework(t.stats.WeeklyStats, weekKey, origin.author)
+	periodKey, _ := periodBucket(when, t.stats.Period, t.stats.WeekStart)
+	creditPeriodRework(t.stats.Periods, periodKey, origin.author)
+}
+
+// creditPeriodRework adds a reworked line to an author's stats for a period
+func creditPeriodRework(series map[string]*PeriodStats, key string, authorName string) {
+	if periodStats, ok := series[key]; ok {
+		if author, ok := periodStats.Authors[authorName]; ok {
+			author.ReworkedLines++
+		}
+	}
+}
+
+// reworkPercent returns the share of added lines that were reworked
+func reworkPercent(reworked, added int) float64 {
+	if added == 0 {
+		return 0
+	}
+	return float64(reworked) / float64(added) * 100
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+func TestReworkTracker(t *testing.T) {
+	r := newTestRepo(t)
+	start := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	r.commit("Alice", start, map[string]string{"a.txt": "one\ntwo\nthree\nfour\n"})
+	// Bob rewrites two of Alice's lines within a week
+	r.commit("Bob", start.AddDate(0, 0, 5), map[string]string{"a.txt": "one\ntwo\nTHREE\nFOUR\n"})
+	// Alice replaces one of Bob's lines two months later, outside the window
+	r.commit("Alice", start.AddDate(0, 2, 0), map[string]string{"a.txt": "one\ntwo\nTHREE\nfour again\n"})
+
+	stats := newTestStats()
+	stats.ReworkWindow = DefaultReworkWindow
+	rework := NewReworkTracker(stats)
+	err := WalkCommits(r.repo, stats, func(record *CommitRecord) error {
+		aggregateCommit(stats, record)
+		return rework.Add(record)
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+	rework.Finish()
+
+	alice := stats.Authors["Alice"]
+	if alice.ReworkedLines != 2 {
+		t.Errorf("Expected 2 of Alice's lines to be reworked, got %d", alice.ReworkedLines)
+	}
+	if bob := stats.Authors["Bob"]; bob.ReworkedLines != 0 {
+		t.Errorf("Expected Bob's line changed after the window not to count, got %d", bob.ReworkedLines)
+	}
+
+	// The rework is credited to the week Alice wrote the lines
+	week := stats.WeeklyStats["2025-W10"]
+	if week == nil || week.Authors["Alice"].ReworkedLines != 2 {
+		t.Errorf("Expected rework in Alice's week 2025-W10, got %+v", week)
+	}
+
+	records := authorRecords(stats)
+	for _, record := range records {
+		if record.ReworkPercent == nil {
+			t.Errorf("Expected rework percentage for %s", record.Author)
+		}
+	}
+}
+
+func TestReworkTrackerUnknownLines(t *testing.T) {
+	stats := newTestStats()
+	stats.ReworkWindow = DefaultReworkWindow
+	tracker := NewReworkTracker(stats)
+
+	// Deleting lines with no known origin must not panic or count as rework
+	lines := tracker.apply(nil, &CommitRecord{Author: "Bob", When: time.Now()}, []lineOp{{Type: fdiff.Equal, Lines: 2}})
+	if len(lines) != 2 || lines[0].author != "" {
+		t.Errorf("Expected 2 kept lines with unknown origin, got %+v", lines)
+	}
+}
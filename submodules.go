@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// withSubmodules adds the submodules of every target after it, recursively.
+// Submodules are analyzed over the time window of their superproject: the
+// requested date range, or the dates of the superproject's first and last
+// commits where no range was given. They are labeled by their path, prefixed
+// with the superproject's label when there are several superprojects.
+// Submodules that are not checked out are reported to warnings and skipped.
+func withSubmodules(targets []repositoryTarget, settings *RepositoryStats, warnings io.Writer) ([]repositoryTarget, error) {
+	var expanded []repositoryTarget
+	for _, target := range targets {
+		repo, err := git.PlainOpen(target.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Path, err)
+		}
+		since, until, err := superprojectWindow(repo, settings)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Path, err)
+		}
+
+		prefix := ""
+		if len(targets) > 1 {
+			prefix = target.Label
+		}
+		expanded = append(expanded, target)
+		if expanded, err = appendSubmodules(expanded, repo, target.Path, prefix, since, until, warnings); err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Path, err)
+		}
+	}
+	return expanded, nil
+}
+
+// appendSubmodules appends the checked-out submodules of a repository, and
+// their submodules in turn, as targets analyzed over the given window
+func appendSubmodules(targets []repositoryTarget, repo *git.Repository, repoPath, prefix string, since, until time.Time, warnings io.Writer) ([]repositoryTarget, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, submodule := range submodules {
+		subPath := submodule.Config().Path
+		label := path.Join(prefix, subPath)
+		fullPath := filepath.Join(repoPath, filepath.FromSlash(subPath))
+
+		subRepo, err := git.PlainOpen(fullPath)
+		if err != nil {
+			fmt.Fprintf(warnings, "Skipping submodule %s: not checked out (run git submodule update --init)\n", label)
+			continue
+		}
+		targets = append(targets, repositoryTarget{Path: fullPath, Label: label, Since: since, Until: until})
+		if targets, err = appendSubmodules(targets, subRepo, fullPath, label, since, until, warnings); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// superprojectWindow returns the date range submodules are analyzed over: the
+// requested range, with open ends closed at the superproject's first and last
+// commit in range. Until is exclusive, like RepositoryStats.Until.
+func superprojectWindow(repo *git.Repository, settings *RepositoryStats) (since, until time.Time, err error) {
+	since, until = settings.Since, settings.Until
+	if !since.IsZero() && !until.IsZero() {
+		return since, until, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return since, until, err
+	}
+	commitIter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return since, until, err
+	}
+	defer commitIter.Close()
+
+	var first, last time.Time
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		when := c.Author.When
+		if !inDateRange(settings, when) {
+			return nil
+		}
+		if first.IsZero() || when.Before(first) {
+			first = when
+		}
+		if when.After(last) {
+			last = when
+		}
+		return nil
+	})
+	if err != nil || first.IsZero() {
+		return since, until, err
+	}
+
+	if since.IsZero() {
+		since = first
+	}
+	if until.IsZero() {
+		until = last.Add(time.Nanosecond)
+	}
+	return since, until, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestWithSubmodules(t *testing.T) {
+	start := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	super := newTestRepo(t)
+	super.commit("alice", start, map[string]string{
+		".gitmodules": "[submodule \"libs/core\"]\n\tpath = libs/core\n\turl = ../core\n" +
+			"[submodule \"libs/missing\"]\n\tpath = libs/missing\n\turl = ../missing\n",
+	})
+	super.commit("alice", start.AddDate(0, 1, 0), map[string]string{"main.go": "package main\n"})
+
+	// Check out libs/core with a nested submodule of its own
+	corePath := filepath.Join(super.dir, "libs", "core")
+	repo, err := git.PlainInit(corePath, false)
+	if err != nil {
+		t.Fatalf("Failed to initialize submodule: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := &testRepo{t: t, dir: corePath, repo: repo, wt: wt}
+	core.commit("bob", start, map[string]string{".gitmodules": "[submodule \"vendor\"]\n\tpath = vendor\n\turl = ../vendor\n"})
+	if _, err := git.PlainInit(filepath.Join(corePath, "vendor"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := newTestStats()
+	var warnings bytes.Buffer
+	targets, err := withSubmodules(repositoryTargets([]string{super.dir}), settings, &warnings)
+	if err != nil {
+		t.Fatalf("Failed to find submodules: %v", err)
+	}
+
+	var labels []string
+	for _, target := range targets {
+		labels = append(labels, target.Label)
+	}
+	expected := []string{filepath.Base(super.dir), "libs/core", "libs/core/vendor"}
+	if strings.Join(labels, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected targets %v, got %v", expected, labels)
+	}
+	if !strings.Contains(warnings.String(), "libs/missing") {
+		t.Errorf("Expected a warning for the submodule that is not checked out, got %q", warnings.String())
+	}
+
+	// Submodules are analyzed over the superproject's history
+	sub := targets[1]
+	if !sub.Since.Equal(start) || !sub.Until.Equal(start.AddDate(0, 1, 0).Add(time.Nanosecond)) {
+		t.Errorf("Expected the superproject's window, got %v to %v", sub.Since, sub.Until)
+	}
+}
+
+func TestSuperprojectWindow(t *testing.T) {
+	start := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	r := newTestRepo(t)
+	r.commit("alice", start, map[string]string{"a.txt": "a\n"})
+	r.commit("alice", start.AddDate(0, 2, 0), map[string]string{"a.txt": "b\n"})
+
+	settings := newTestStats()
+	settings.Since = start.AddDate(0, 1, 0)
+	since, until, err := superprojectWindow(r.repo, settings)
+	if err != nil {
+		t.Fatalf("Failed to compute window: %v", err)
+	}
+	if !since.Equal(settings.Since) {
+		t.Errorf("Expected the requested start to be kept, got %v", since)
+	}
+	if !until.Equal(start.AddDate(0, 2, 0).Add(time.Nanosecond)) {
+		t.Errorf("Expected the window to end after the last commit, got %v", until)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// unassignedTeam is the team of authors missing from the teams file
+const unassignedTeam = "(unassigned)"
+
+// TeamMap maps author identities to team names
+type TeamMap struct {
+	members map[string]string // Lower-case name or email to team
+}
+
+// loadTeams reads a teams file. Each line maps an author name or email to a
+// team as "identity = team"; blank lines and lines starting with # are ignored.
+//
+//	alice@example.com = Platform
+//	Bob Smith         = Web
+func loadTeams(filename string) (*TeamMap, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	teams := &TeamMap{members: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		identity, team, ok := strings.Cut(line, "=")
+		identity, team = strings.TrimSpace(identity), strings.TrimSpace(team)
+		if !ok || identity == "" || team == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"identity = team\"", filename, lineNumber)
+		}
+		teams.members[strings.ToLower(identity)] = team
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// Team returns the team of an author, matching the email before the name
+func (m *TeamMap) Team(name, email string) string {
+	if team, ok := m.members[strings.ToLower(email)]; ok {
+		return team
+	}
+	if team, ok := m.members[strings.ToLower(name)]; ok {
+		return team
+	}
+	return unassignedTeam
+}
+
+// Apply returns a copy of a commit record credited to the author's team
+func (m *TeamMap) Apply(record *CommitRecord) *CommitRecord {
+	teamRecord := *record
+	teamRecord.Author = m.Team(record.Author, record.Email)
+	return &teamRecord
+}
+
+// displayPeriodMatrix displays a time series as a matrix of lines changed,
+// with one row per period and one column per author or team
+func displayPeriodMatrix(stats *RepositoryStats, series map[string]*PeriodStats, period Period) {
+	// Order the columns by lines changed over the whole range
+	authors := make([]*AuthorStats, 0, len(stats.Authors))
+	for _, author := range stats.Authors {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].LinesChanged != authors[j].LinesChanged {
+			return authors[i].LinesChanged > authors[j].LinesChanged
+		}
+		return authors[i].Name < authors[j].Name
+	})
+
+	header := []string{period.Label()}
+	for _, author := range authors {
+		header = append(header, author.Name)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(append(header, "TOTAL"))
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+
+	for _, periodStats := range sortedPeriods(series) {
+		row := []string{periodStats.Start.Format("2006-01-02")}
+		for _, author := range authors {
+			lines := 0
+			if periodAuthor, ok := periodStats.Authors[author.Name]; ok {
+				lines = periodAuthor.LinesChanged
+			}
+			row = append(row, fmt.Sprintf("%d", lines))
+		}
+		table.Append(append(row, fmt.Sprintf("%d", periodStats.TotalLines)))
+	}
+
+	// Finish with each column's share of all lines changed
+	row := []string{"SHARE"}
+	for _, author := range authors {
+		share := 0.0
+		if stats.TotalLines > 0 {
+			share = float64(author.LinesChanged) / float64(stats.TotalLines) * 100
+		}
+		row = append(row, fmt.Sprintf("%.1f%%", share))
+	}
+	table.Append(append(row, "100%"))
+	table.Render()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadTeams(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "teams")
+	content := "# Platform team\nalice@example.com = Platform\nBob Smith = Web\n\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	teams, err := loadTeams(filename)
+	if err != nil {
+		t.Fatalf("Failed to load teams: %v", err)
+	}
+	tests := []struct {
+		name, email, expected string
+	}{
+		{"Alice", "Alice@Example.com", "Platform"},
+		{"bob smith", "bob@example.com", "Web"},
+		{"Carol", "carol@example.com", unassignedTeam},
+	}
+	for _, test := range tests {
+		if team := teams.Team(test.name, test.email); team != test.expected {
+			t.Errorf("Expected %s to be in %q, got %q", test.name, test.expected, team)
+		}
+	}
+
+	if err := os.WriteFile(filename, []byte("alice@example.com Platform\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTeams(filename); err == nil {
+		t.Errorf("Expected an error for a line without =")
+	}
+}
+
+func TestTeamAggregation(t *testing.T) {
+	teams := &TeamMap{members: map[string]string{"alice": "Platform", "bob": "Platform", "carol": "Web"}}
+	stats := newTestStats()
+	stats.ByTeam = true
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	records := []*CommitRecord{
+		{Author: "Alice", When: when, Files: []FileChange{{Name: "a.go", Additions: 10}}},
+		{Author: "Bob", When: when, Files: []FileChange{{Name: "b.go", Additions: 5}}},
+		{Author: "Carol", When: when.AddDate(0, 0, 7), Files: []FileChange{{Name: "c.ts", Additions: 15}}},
+	}
+	for _, record := range records {
+		aggregateCommit(stats, teams.Apply(record))
+	}
+
+	platform, web := stats.Authors["Platform"], stats.Authors["Web"]
+	if len(stats.Authors) != 2 || platform == nil || web == nil {
+		t.Fatalf("Expected two teams, got %v", stats.Authors)
+	}
+	if platform.CommitCount != 2 || platform.LinesChanged != 15 || web.LinesChanged != 15 {
+		t.Errorf("Unexpected team stats: %+v %+v", platform, web)
+	}
+	if len(stats.WeeklyStats) != 2 || stats.WeeklyStats["2024-W10"].Authors["Platform"].LinesChanged != 15 {
+		t.Errorf("Unexpected weekly team stats: %v", stats.WeeklyStats)
+	}
+
+	// The original record keeps its author
+	if records[0].Author != "Alice" {
+		t.Errorf("Expected Apply to leave the record unchanged, got %q", records[0].Author)
+	}
+}
@@ -136,7 +136,7 @@ func TestJoeMainFunctionality(t *testing.T) {
 	}
 
 	// Analyze the repository
-	err = gitstics.AnalyzeRepository(repo, stats)
+	err = gitstics.ExportedAnalyzeRepository(repo, stats)
 	if err != nil {
 		t.Fatalf("Failed to analyze repository: %v", err)
 	}
@@ -165,7 +165,7 @@ func TestJoeMainFunctionality(t *testing.T) {
 	os.Stdout = pipeWriter
 
 	// Display the stats
-	gitstics.DisplayStats(stats)
+	gitstics.ExportedDisplayStats(stats)
 
 	// Restore stdout
 	pipeWriter.Close()
@@ -250,7 +250,7 @@ func TestJoeWeeklyStats(t *testing.T) {
 	}
 
 	// Analyze the repository
-	err = gitstics.AnalyzeRepository(repo, stats)
+	err = gitstics.ExportedAnalyzeRepository(repo, stats)
 	if err != nil {
 		t.Fatalf("Failed to analyze repository: %v", err)
 	}
@@ -261,7 +261,7 @@ func TestJoeWeeklyStats(t *testing.T) {
 	os.Stdout = pipeWriter
 
 	// Display the weekly stats
-	gitstics.DisplayWeeklyStats(stats)
+	gitstics.ExportedDisplayWeeklyStats(stats)
 
 	// Restore stdout
 	pipeWriter.Close()
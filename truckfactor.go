@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// ownerShareThreshold is how large an author's share of a file must be,
+// relative to the file's top author, for the author to count as an owner
+const ownerShareThreshold = 0.75
+
+// SingleOwnerFile is a file that only one author knows
+type SingleOwnerFile struct {
+	Path  string
+	Owner string
+	Share float64 // Fraction of the file's surviving lines written by the owner
+}
+
+// DirectoryTruckFactor holds the smallest set of authors covering more than
+// half of the files in a directory
+type DirectoryTruckFactor struct {
+	Directory string
+	Files     int
+	Authors   []string
+	Covered   int // Files owned by at least one of Authors
+}
+
+// TruckFactorStats holds the truck factor of a repository
+type TruckFactorStats struct {
+	TruckFactor      int      // Authors that must leave before more than half the files are orphaned
+	Authors          []string // The authors in the order they were removed
+	TotalFiles       int
+	Directories      []*DirectoryTruckFactor
+	SingleOwnerFiles []*SingleOwnerFile
+}
+
+// fileOwners returns the authors owning a file: its top author and everyone
+// whose share is at least ownerShareThreshold of the top author's share
+func fileOwners(file *FileOwnership) []string {
+	top := 0
+	for _, count := range file.Lines {
+		if count > top {
+			top = count
+		}
+	}
+	if top == 0 {
+		return nil
+	}
+
+	owners := []string{}
+	for name, count := range file.Lines {
+		if float64(count) >= float64(top)*ownerShareThreshold {
+			owners = append(owners, name)
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// CalculateTruckFactor computes the truck factor from per-file blame ownership.
+// Authors owning the most files are removed one at a time until more than half
+// of the files have no owner left.
+func CalculateTruckFactor(ownership *OwnershipStats) *TruckFactorStats {
+	result := &TruckFactorStats{TotalFiles: len(ownership.Files)}
+
+	// Work out who owns each file
+	owners := make(map[string][]string, len(ownership.Files))
+	byDirectory := make(map[string][]string)
+	for _, file := range ownership.Files {
+		fileOwnerList := fileOwners(file)
+		if len(fileOwnerList) == 0 {
+			continue
+		}
+		owners[file.Path] = fileOwnerList
+		dir := path.Dir(file.Path)
+		byDirectory[dir] = append(byDirectory[dir], file.Path)
+
+		if len(fileOwnerList) == 1 {
+			result.SingleOwnerFiles = append(result.SingleOwnerFiles, &SingleOwnerFile{
+				Path:  file.Path,
+				Owner: fileOwnerList[0],
+				Share: float64(file.Lines[fileOwnerList[0]]) / float64(file.TotalLines),
+			})
+		}
+	}
+	sort.Slice(result.SingleOwnerFiles, func(i, j int) bool {
+		return result.SingleOwnerFiles[i].Path < result.SingleOwnerFiles[j].Path
+	})
+
+	// Remove top owners until more than half of the files are orphaned
+	removed := make(map[string]bool)
+	for orphanedFiles(owners, removed)*2 <= len(owners) {
+		author := topOwner(owners, removed, nil)
+		if author == "" {
+			break
+		}
+		removed[author] = true
+		result.Authors = append(result.Authors, author)
+	}
+	result.TruckFactor = len(result.Authors)
+
+	// Find the smallest set of owners covering more than half of each directory
+	for dir, files := range byDirectory {
+		dirOwners := make(map[string][]string, len(files))
+		for _, file := range files {
+			dirOwners[file] = owners[file]
+		}
+
+		directory := &DirectoryTruckFactor{Directory: dir, Files: len(files)}
+		covered := make(map[string]bool)
+		for directory.Covered*2 <= directory.Files {
+			author := topOwner(dirOwners, nil, covered)
+			if author == "" {
+				break
+			}
+			directory.Authors = append(directory.Authors, author)
+			for file, fileOwnerList := range dirOwners {
+				if !covered[file] && containsString(fileOwnerList, author) {
+					covered[file] = true
+					directory.Covered++
+				}
+			}
+		}
+		result.Directories = append(result.Directories, directory)
+	}
+	sort.Slice(result.Directories, func(i, j int) bool {
+		return result.Directories[i].Directory < result.Directories[j].Directory
+	})
+
+	return result
+}
+
+// orphanedFiles counts the files whose owners have all been removed
+func orphanedFiles(owners map[string][]string, removed map[string]bool) int {
+	orphaned := 0
+	for _, fileOwnerList := range owners {
+		remaining := false
+		for _, owner := range fileOwnerList {
+			if !removed[owner] {
+				remaining = true
+				break
+			}
+		}
+		if !remaining {
+			orphaned++
+		}
+	}
+	return orphaned
+}
+
+// topOwner returns the author owning the most files, ignoring removed authors
+// and files that are already covered. Ties are broken alphabetically.
+func topOwner(owners map[string][]string, removed map[string]bool, covered map[string]bool) string {
+	counts := make(map[string]int)
+	for file, fileOwnerList := range owners {
+		if covered[file] {
+			continue
+		}
+		for _, owner := range fileOwnerList {
+			if !removed[owner] {
+				counts[owner]++
+			}
+		}
+	}
+
+	top := ""
+	for owner, count := range counts {
+		if top == "" || count > counts[top] || (count == counts[top] && owner < top) {
+			top = owner
+		}
+	}
+	return top
+}
+
+// containsString reports whether the slice contains the given string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTruckFactor writes the truck factor report in the requested format
+func writeTruckFactor(truckFactor *TruckFactorStats, format string) error {
+	switch format {
+	case FormatJSON:
+		type directoryRecord struct {
+			Directory string   `json:"directory"`
+			Files     int      `json:"files"`
+			Covered   int      `json:"covered_files"`
+			Authors   []string `json:"authors"`
+		}
+		type singleOwnerRecord struct {
+			Path  string  `json:"path"`
+			Owner string  `json:"owner"`
+			Share float64 `json:"share"`
+		}
+		directories := []directoryRecord{}
+		for _, d := range truckFactor.Directories {
+			directories = append(directories, directoryRecord{d.Directory, d.Files, d.Covered, d.Authors})
+		}
+		singleOwnerFiles := []singleOwnerRecord{}
+		for _, f := range truckFactor.SingleOwnerFiles {
+			singleOwnerFiles = append(singleOwnerFiles, singleOwnerRecord{f.Path, f.Owner, f.Share})
+		}
+		return writeJSON(struct {
+			TruckFactor      int                 `json:"truck_factor"`
+			Authors          []string            `json:"authors"`
+			TotalFiles       int                 `json:"total_files"`
+			Directories      []directoryRecord   `json:"directories"`
+			SingleOwnerFiles []singleOwnerRecord `json:"single_owner_files"`
+		}{truckFactor.TruckFactor, truckFactor.Authors, truckFactor.TotalFiles, directories, singleOwnerFiles})
+	case FormatCSV:
+		rows := [][]string{{"scope", "path", "files", "covered_files", "authors", "share"}}
+		rows = append(rows, []string{"repository", "", fmt.Sprintf("%d", truckFactor.TotalFiles), "", strings.Join(truckFactor.Authors, ";"), ""})
+		for _, d := range truckFactor.Directories {
+			rows = append(rows, []string{"directory", d.Directory, fmt.Sprintf("%d", d.Files), fmt.Sprintf("%d", d.Covered), strings.Join(d.Authors, ";"), ""})
+		}
+		for _, f := range truckFactor.SingleOwnerFiles {
+			rows = append(rows, []string{"file", f.Path, "", "", f.Owner, fmt.Sprintf("%.3f", f.Share)})
+		}
+		return writeCSV(rows)
+	default:
+		displayTruckFactor(truckFactor)
+		return nil
+	}
+}
+
+// displayTruckFactor displays the truck factor report in ASCII tables
+func displayTruckFactor(truckFactor *TruckFactorStats) {
+	fmt.Printf("Truck factor: %d (%s)\n", truckFactor.TruckFactor, strings.Join(truckFactor.Authors, ", "))
+
+	// Authors covering each directory
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Directory", "Files", "Key Authors", "Files Covered %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, d := range truckFactor.Directories {
+		coverage := 0.0
+		if d.Files > 0 {
+			coverage = float64(d.Covered) / float64(d.Files) * 100
+		}
+		table.Append([]string{
+			d.Directory,
+			fmt.Sprintf("%d", d.Files),
+			strings.Join(d.Authors, ", "),
+			fmt.Sprintf("%.1f%%", coverage),
+		})
+	}
+	table.Render()
+
+	// Files known by a single author
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Single-Owner File", "Owner", "Share %"})
+	table.SetBorder(true)
+	table.SetAutoFormatHeaders(false)
+	for _, f := range truckFactor.SingleOwnerFiles {
+		table.Append([]string{f.Path, f.Owner, fmt.Sprintf("%.1f%%", f.Share*100)})
+	}
+	table.Render()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCalculateTruckFactor(t *testing.T) {
+	ownership := &OwnershipStats{
+		Files: []*FileOwnership{
+			{Path: "api/a.go", Lines: map[string]int{"Alice": 90, "Bob": 10}, TotalLines: 100},
+			{Path: "api/b.go", Lines: map[string]int{"Alice": 50, "Bob": 45}, TotalLines: 95},
+			{Path: "api/c.go", Lines: map[string]int{"Bob": 30}, TotalLines: 30},
+			{Path: "web/d.ts", Lines: map[string]int{"Carol": 40, "Alice": 5}, TotalLines: 45},
+			{Path: "web/e.ts", Lines: map[string]int{"Carol": 20}, TotalLines: 20},
+		},
+	}
+
+	result := CalculateTruckFactor(ownership)
+
+	// Removing Alice orphans a.go; removing Bob as well orphans b.go and c.go
+	if result.TruckFactor != 2 {
+		t.Errorf("Expected a truck factor of 2, got %d (%v)", result.TruckFactor, result.Authors)
+	}
+	if !reflect.DeepEqual(result.Authors, []string{"Alice", "Bob"}) {
+		t.Errorf("Unexpected truck factor authors: %v", result.Authors)
+	}
+
+	if len(result.Directories) != 2 {
+		t.Fatalf("Expected 2 directories, got %d", len(result.Directories))
+	}
+	// Alice alone owns two of the three api files
+	api := result.Directories[0]
+	if api.Directory != "api" || !reflect.DeepEqual(api.Authors, []string{"Alice"}) || api.Covered != 2 {
+		t.Errorf("Unexpected api directory result: %+v", api)
+	}
+	web := result.Directories[1]
+	if web.Directory != "web" || !reflect.DeepEqual(web.Authors, []string{"Carol"}) {
+		t.Errorf("Unexpected web directory result: %+v", web)
+	}
+
+	// b.go is shared by Alice and Bob; every other file has a single owner
+	if len(result.SingleOwnerFiles) != 4 {
+		t.Errorf("Expected 4 single-owner files, got %d", len(result.SingleOwnerFiles))
+	}
+	for _, file := range result.SingleOwnerFiles {
+		if file.Path == "api/b.go" {
+			t.Errorf("Did not expect api/b.go to have a single owner")
+		}
+	}
+}
+
+func TestCalculateTruckFactorEmpty(t *testing.T) {
+	result := CalculateTruckFactor(&OwnershipStats{})
+	if result.TruckFactor != 0 || len(result.Directories) != 0 {
+		t.Errorf("Expected an empty result, got %+v", result)
+	}
+}
+
+func TestWriteTruckFactorCSV(t *testing.T) {
+	truckFactor := &TruckFactorStats{
+		TruckFactor: 1,
+		Authors:     []string{"Alice"},
+		TotalFiles:  2,
+		Directories: []*DirectoryTruckFactor{{Directory: "api", Files: 2, Covered: 2, Authors: []string{"Alice"}}},
+		SingleOwnerFiles: []*SingleOwnerFile{
+			{Path: "api/a.go", Owner: "Alice", Share: 0.9},
+		},
+	}
+
+	output := captureStdout(t, func() error { return writeTruckFactor(truckFactor, FormatCSV) })
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV output: %v", err)
+	}
+	expected := [][]string{
+		{"scope", "path", "files", "covered_files", "authors", "share"},
+		{"repository", "", "2", "", "Alice", ""},
+		{"directory", "api", "2", "2", "Alice", ""},
+		{"file", "api/a.go", "", "", "Alice", "0.900"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected CSV rows %v, got %v", expected, rows)
+	}
+}
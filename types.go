@@ -4,33 +4,140 @@ import "time"
 
 // AuthorStats holds statistics for a single author
 type AuthorStats struct {
-	Name         string
-	CommitCount  int
-	LinesChanged int
+	Name          string
+	CommitCount   int
+	LinesChanged  int
+	LinesAdded    int
+	ReworkedLines int       // Added lines changed again within the rework window
+	FirstCommit   time.Time // Author date of the author's earliest analyzed commit
+	LastCommit    time.Time // Author date of the author's latest analyzed commit
+
+	CodeLinesChanged int            // Changed lines holding code, when lines are classified
+	Bot              bool           // The author is a bot or automation account
+	Emails           map[string]int // Commits per lower-case email address
+	Repositories     map[string]int // Lines changed per repository, when several are combined
 }
 
-// WeeklyAuthorStats holds statistics for a single author for a specific week
-type WeeklyAuthorStats struct {
+// FileStats holds the change history of a single file
+type FileStats struct {
 	Name         string
-	CommitCount  int
-	LinesChanged int
-	Week         time.Time // Start of the week (Sunday)
+	CommitCount  int // Commits that changed the file
+	LinesAdded   int
+	LinesDeleted int
+	Authors      map[string]int // Commits per author
 }
 
-// WeeklyStats holds statistics for a specific week
-type WeeklyStats struct {
-	Week         time.Time // Start of the week (Sunday)
-	Authors      map[string]*WeeklyAuthorStats
+// PeriodAuthorStats holds statistics for a single author for a specific period
+type PeriodAuthorStats struct {
+	Name          string
+	CommitCount   int
+	LinesChanged  int
+	LinesAdded    int
+	ReworkedLines int       // Lines added in this period changed again within the rework window
+	Start         time.Time // Start of the period
+	Week          time.Time // Same as Start, kept for callers of WeeklyAuthorStats
+
+	CodeLinesChanged int            // Changed lines holding code, when lines are classified
+	Repositories     map[string]int // Lines changed per repository, when several are combined
+}
+
+// PeriodStats holds statistics for a specific period of a time series
+type PeriodStats struct {
+	Key          string    // Bucket key, e.g. "2025-W14", "2025-04" or "2025-Q2"
+	Start        time.Time // Start of the period
+	Week         time.Time // Same as Start, kept for callers of WeeklyStats
+	Authors      map[string]*PeriodAuthorStats
 	TotalCommits int
 	TotalLines   int
 }
 
+// WeeklyAuthorStats holds statistics for a single author for a specific week
+type WeeklyAuthorStats = PeriodAuthorStats
+
+// WeeklyStats holds statistics for a specific week
+type WeeklyStats = PeriodStats
+
+// FileChange holds the lines added and removed in a single file by a commit
+type FileChange struct {
+	Name      string `json:"name"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+
+	// Added and deleted lines by kind, only counted when lines are classified
+	CodeLines    int `json:"code_lines,omitempty"`
+	CommentLines int `json:"comment_lines,omitempty"`
+	BlankLines   int `json:"blank_lines,omitempty"`
+
+	ops []lineOp // Line-level edits, only kept when rework is tracked
+}
+
+// CommitRecord holds the per-commit data for a commit that passed the filters
+type CommitRecord struct {
+	Hash   string       `json:"hash"`
+	Author string       `json:"author"`
+	Email  string       `json:"email"`
+	When   time.Time    `json:"time"`
+	Files  []FileChange `json:"files"`
+}
+
+// LinesChanged returns the total number of lines added and removed by the commit
+func (r *CommitRecord) LinesChanged() int {
+	lines := 0
+	for _, file := range r.Files {
+		lines += file.Additions + file.Deletions
+	}
+	return lines
+}
+
+// CodeLinesChanged returns the number of added and removed lines holding code.
+// It is only meaningful when lines are classified.
+func (r *CommitRecord) CodeLinesChanged() int {
+	lines := 0
+	for _, file := range r.Files {
+		lines += file.CodeLines
+	}
+	return lines
+}
+
+// LinesAdded returns the total number of lines added by the commit
+func (r *CommitRecord) LinesAdded() int {
+	lines := 0
+	for _, file := range r.Files {
+		lines += file.Additions
+	}
+	return lines
+}
+
 // RepositoryStats holds statistics for the entire repository
 type RepositoryStats struct {
-	Authors      map[string]*AuthorStats
-	WeeklyStats  map[string]*WeeklyStats // Key is ISO week string "YYYY-WW"
-	TotalCommits int
-	TotalLines   int
-	FileFilter   string
-	IgnoreFiles  map[string]bool
+	Authors          map[string]*AuthorStats
+	WeeklyStats      map[string]*WeeklyStats // Key is ISO week string "YYYY-WW"
+	Periods          map[string]*PeriodStats // Time series bucketed by Period
+	Files            map[string]*FileStats   // Per-file churn, keyed by path
+	Period           Period                  // Bucket size for Periods, weekly when empty
+	WeekStart        WeekStart               // First day of weekly buckets, Monday when empty
+	Location         *time.Location          // Time zone used for bucketing, commit's own offset when nil
+	Since            time.Time               // Only analyze commits authored at or after Since (if set)
+	Until            time.Time               // Only analyze commits authored before Until (if set)
+	Revision         string                  // Walk the history of this branch, tag or commit instead of HEAD (if set)
+	BaseRevision     string                  // Leave out commits in the history of this revision (if set)
+	ReworkWindow     time.Duration           // Track rework of lines changed again within this window (if set)
+	ClassifyLines    bool                    // Classify changed lines as code, comment or blank
+	SkipNonCode      bool                    // Skip commits changing only comments and blank lines (needs ClassifyLines)
+	IgnoreWhitespace bool                    // Ignore whitespace and line-ending differences when diffing
+	IgnoreRevs       map[string]bool         // Commit hashes to leave out of every statistic
+	Bots             BotMode                 // How bot commits are handled, included when empty
+	BotAuthors       map[string]bool         // Lower-case names and emails of additional bots
+	ByTeam           bool                    // Authors are teams rather than people (see TeamMap)
+	Repositories     []string                // Labels of the combined repositories, in column order
+	Aliases          map[string]string       // Lower-case names and emails to canonical author names
+	SkipMerges       bool                    // Leave merge commits out of every statistic
+	IgnoredCommits   int                     // Commits skipped because they are in IgnoreRevs
+	TotalCommits     int
+	TotalLines       int
+	TotalCodeLines   int // Changed lines holding code, when lines are classified
+	FileFilter       string
+	IgnoreFiles      map[string]bool
+	IgnoreGlobs      []string     // Gitignore-style patterns of files to leave out
+	OnProgress       ProgressFunc // Optional callback invoked after each processed commit
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// normalizeWhitespace removes all whitespace from every line of content, so
+// that lines differing only in indentation, spacing or line endings compare
+// equal. Every line, including the last one, ends in a newline.
+func normalizeWhitespace(content string) string {
+	var normalized strings.Builder
+	for _, line := range splitLines(content) {
+		normalized.WriteString(normalizeLine(line))
+		normalized.WriteByte('\n')
+	}
+	return normalized.String()
+}
+
+// normalizeLine removes all whitespace from a line
+func normalizeLine(line string) string {
+	return strings.Join(strings.Fields(line), "")
+}
+
+// textChunk is a chunk of a patch computed by gitstics itself
+type textChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c textChunk) Content() string       { return c.content }
+func (c textChunk) Type() fdiff.Operation { return c.op }
+
+// textFilePatch is a file patch computed by gitstics itself
+type textFilePatch struct {
+	from, to fdiff.File
+	chunks   []fdiff.Chunk
+}
+
+func (p textFilePatch) IsBinary() bool                  { return false }
+func (p textFilePatch) Files() (fdiff.File, fdiff.File) { return p.from, p.to }
+func (p textFilePatch) Chunks() []fdiff.Chunk           { return p.chunks }
+
+// ignoreWhitespacePatch recomputes a file patch on whitespace-normalized
+// content. Binary files and patches without content are returned unchanged.
+func ignoreWhitespacePatch(repo *git.Repository, fp fdiff.FilePatch) (fdiff.FilePatch, error) {
+	if fp.IsBinary() || len(fp.Chunks()) == 0 {
+		return fp, nil
+	}
+
+	from, to := fp.Files()
+	src, err := blobContent(repo, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := blobContent(repo, to)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := textFilePatch{from: from, to: to}
+	for _, d := range diff.Do(normalizeWhitespace(src), normalizeWhitespace(dst)) {
+		chunk := textChunk{content: d.Text, op: fdiff.Equal}
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			chunk.op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			chunk.op = fdiff.Delete
+		}
+		patch.chunks = append(patch.chunks, chunk)
+	}
+	return patch, nil
+}
+
+// blobContent reads the content of one side of a file patch, which is empty
+// for added and deleted files
+func blobContent(repo *git.Repository, file fdiff.File) (string, error) {
+	if file == nil {
+		return "", nil
+	}
+	blob, err := repo.BlobObject(file.Hash())
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", file.Path(), err)
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", file.Path(), err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", file.Path(), err)
+	}
+	return string(content), nil
+}
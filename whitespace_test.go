@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeWhitespace(t *testing.T) {
+	normalized := normalizeWhitespace("func main() {\r\n\tx := 1\r\n}")
+	if normalized != "funcmain(){\nx:=1\n}\n" {
+		t.Errorf("Unexpected normalized content: %q", normalized)
+	}
+	if normalizeWhitespace("a = b\n") != normalizeWhitespace("a=b") {
+		t.Errorf("Expected spacing and a missing final newline to be ignored")
+	}
+}
+
+func TestWalkCommitsIgnoreWhitespace(t *testing.T) {
+	repo := newTestRepo(t)
+	when := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.commit("Alice", when, map[string]string{
+		"main.go": "package main\n\nfunc main() {\nprintln(1)\nprintln(2)\n}\n",
+	})
+	// Bob reformats the file and converts it to CRLF line endings
+	repo.commit("Bob", when.Add(time.Hour), map[string]string{
+		"main.go": "package main\r\n\r\nfunc main() {\r\n\tprintln(1)\r\n\tprintln(2)\r\n}\r\n",
+	})
+	// Carol reformats and changes one line
+	repo.commit("Carol", when.Add(2*time.Hour), map[string]string{
+		"main.go": "package main\n\nfunc main() {\n    println(1)\n    println(3)\n}\n",
+	})
+
+	stats := newTestStats()
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	if stats.Authors["Bob"].LinesChanged != 12 {
+		t.Errorf("Expected Bob to change 12 lines by default, got %d", stats.Authors["Bob"].LinesChanged)
+	}
+
+	stats = newTestStats()
+	stats.IgnoreWhitespace = true
+	if err := analyzeRepository(repo.repo, stats); err != nil {
+		t.Fatalf("Failed to analyze repository: %v", err)
+	}
+	// Bob's whitespace-only commit still counts, without changed lines or files
+	if bob := stats.Authors["Bob"]; bob == nil || bob.CommitCount != 1 || bob.LinesChanged != 0 {
+		t.Errorf("Expected Bob's whitespace-only commit to count with 0 lines, got %+v", bob)
+	}
+	if carol := stats.Authors["Carol"]; carol == nil || carol.LinesChanged != 2 {
+		t.Errorf("Expected Carol to change 2 lines, got %+v", carol)
+	}
+	if stats.TotalCommits != 3 {
+		t.Errorf("Expected 3 commits, got %d", stats.TotalCommits)
+	}
+	for _, week := range stats.WeeklyStats {
+		if week.TotalCommits != 3 || week.Authors["Bob"] == nil {
+			t.Errorf("Expected Bob's commit in the weekly series, got %d commits", week.TotalCommits)
+		}
+	}
+
+	var bobFiles []FileChange
+	if err := WalkCommits(repo.repo, stats, func(record *CommitRecord) error {
+		if record.Author == "Bob" {
+			bobFiles = record.Files
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+	if len(bobFiles) != 0 {
+		t.Errorf("Expected no file entries for Bob's commit, got %+v", bobFiles)
+	}
+}